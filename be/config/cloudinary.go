@@ -3,7 +3,9 @@ package config
 import (
 	"context"
 	"log"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
@@ -25,10 +27,18 @@ func InitCloudinary() {
 	CloudinaryInstance = cld
 }
 
-// UploadFile mengunggah file (Gambar/Video/Dokumen) ke Cloudinary dan mengembalikan URL-nya
-func UploadFile(ctx context.Context, input interface{}) (string, error) {
+// UploadedFile hasil upload ke Cloudinary. PublicID & ResourceType disimpan untuk menghapus file nanti.
+type UploadedFile struct {
+	URL          string
+	PublicID     string
+	ResourceType string
+}
+
+// UploadFile mengunggah file (Gambar/Video/Dokumen) ke Cloudinary.
+// Mengembalikan nil jika Cloudinary belum dikonfigurasi.
+func UploadFile(ctx context.Context, input interface{}) (*UploadedFile, error) {
 	if CloudinaryInstance == nil {
-		return "", nil
+		return nil, nil
 	}
 
 	result, err := CloudinaryInstance.Upload.Upload(ctx, input, uploader.UploadParams{
@@ -36,8 +46,37 @@ func UploadFile(ctx context.Context, input interface{}) (string, error) {
 		ResourceType: "auto", // Otomatis mendeteksi apakah itu image, video, atau raw (doc)
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return result.SecureURL, nil
+	return &UploadedFile{
+		URL:          result.SecureURL,
+		PublicID:     result.PublicID,
+		ResourceType: result.ResourceType,
+	}, nil
+}
+
+// DeleteFile menghapus file di Cloudinary berdasarkan public ID yang dicatat saat upload.
+// Sengaja tidak menerima URL: URL di isi pesan berasal dari client dan bisa menunjuk file milik orang lain.
+func DeleteFile(ctx context.Context, publicID, resourceType string) error {
+	if CloudinaryInstance == nil {
+		return nil
+	}
+
+	_, err := CloudinaryInstance.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     publicID,
+		ResourceType: resourceType,
+	})
+	return err
+}
+
+// IsOwnMediaURL mengecek apakah URL menunjuk file di akun Cloudinary aplikasi ini
+// (https://res.cloudinary.com/<CLOUDINARY_CLOUD_NAME>/...)
+func IsOwnMediaURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host != "res.cloudinary.com" {
+		return false
+	}
+	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
+	return cloudName != "" && strings.HasPrefix(u.Path, "/"+cloudName+"/")
 }
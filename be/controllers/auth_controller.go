@@ -60,10 +60,19 @@ func (c *AuthController) Register(ctx *gin.Context) {
 	}
 
 	// 2. Simpan user ke database
-	user, err := c.UserRepo.CreateUser(ctx.Request.Context(), input.Email, input.Name, string(hashedPassword), input.Color)
+	newUsername, ok := normalizeUsername(input.Username)
+	if !ok {
+		utils.BadRequest(ctx, "Username tidak tersedia", nil)
+		return
+	}
+	user, err := c.UserRepo.CreateUser(ctx.Request.Context(), input.Email, input.Name, string(hashedPassword), input.Color, newUsername)
 	if err != nil {
 		// Prisma P2002 is Unique constraint failed
 		if strings.Contains(err.Error(), "P2002") {
+			if strings.Contains(err.Error(), "username") {
+				utils.Conflict(ctx, "Username sudah dipakai", err)
+				return
+			}
 			utils.Conflict(ctx, "Email sudah terdaftar", err)
 			return
 		}
@@ -79,6 +88,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 
 	color, _ := user.Color()
 	avatar, _ := user.AvatarURL()
+	username, _ := user.Username()
 
 	utils.CreatedResponse(ctx, "Registrasi berhasil!", gin.H{
 		"token": token,
@@ -86,6 +96,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 			ID:        user.ID,
 			Email:     user.Email,
 			Name:      user.Name,
+			Username:  username,
 			AvatarUrl: avatar,
 			Color:     color,
 		},
@@ -114,6 +125,12 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
+	// Akun yang ditangguhkan moderator tidak bisa login sampai masa penangguhan habis
+	if until, suspended := repositories.ActiveSuspension(user); suspended {
+		utils.Forbidden(ctx, "Akun Anda ditangguhkan sampai "+until.Format(time.RFC3339), nil)
+		return
+	}
+
 	// 3. Generate Token
 	token, _ := c.GenerateToken(user.ID, user.Name)
 
@@ -124,6 +141,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 
 	color, _ := user.Color()
 	avatar, _ := user.AvatarURL()
+	username, _ := user.Username()
 
 	utils.SuccessResponse(ctx, "Login berhasil, silakan verifikasi OTP!", gin.H{
 		"token": token,
@@ -131,6 +149,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 			ID:        user.ID,
 			Email:     user.Email,
 			Name:      user.Name,
+			Username:  username,
 			AvatarUrl: avatar,
 			Color:     color,
 		},
@@ -251,20 +270,49 @@ func (c *AuthController) UpdateProfile(ctx *gin.Context) {
 		return
 	}
 
-	user, err := c.UserRepo.UpdateUser(ctx.Request.Context(), userID, input.Name, input.AvatarUrl)
+	var newUsername *string
+	if input.Username != nil {
+		normalized, ok := normalizeUsername(*input.Username)
+		if !ok {
+			utils.BadRequest(ctx, "Username tidak tersedia", nil)
+			return
+		}
+		newUsername = normalized
+	}
+
+	user, err := c.UserRepo.UpdateUser(ctx.Request.Context(), userID, input.Name, input.AvatarUrl, newUsername)
 	if err != nil {
+		if strings.Contains(err.Error(), "P2002") {
+			utils.Conflict(ctx, "Username sudah dipakai", err)
+			return
+		}
 		utils.InternalError(ctx, "Gagal memperbarui profil", err)
 		return
 	}
 
 	color, _ := user.Color()
 	avatar, _ := user.AvatarURL()
+	username, _ := user.Username()
 
 	utils.SuccessResponse(ctx, "Profil berhasil diperbarui", models.UserResponse{
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Username:  username,
 		AvatarUrl: avatar,
 		Color:     color,
 	})
 }
+
+// normalizeUsername menyimpan username dalam huruf kecil supaya @mention tidak peka huruf besar.
+// Username kosong berarti tidak diisi (nil); "all" ditolak karena dipakai untuk @all.
+func normalizeUsername(raw string) (*string, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	username := strings.ToLower(raw)
+	if username == utils.MentionAll {
+		return nil, false
+	}
+	return &username, true
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status hasil pengiriman broadcast per penerima
+const (
+	broadcastSent    = "SENT"
+	broadcastSkipped = "SKIPPED"
+	broadcastFailed  = "FAILED"
+)
+
+// BroadcastController mengatur daftar broadcast dan pengiriman pesan ke daftar tersebut
+type BroadcastController struct {
+	BroadcastRepo *repositories.BroadcastRepository
+	ChatRepo      *repositories.ChatRepository
+	ContactRepo   *repositories.ContactRepository
+	WS            *WSController
+}
+
+// NewBroadcastController inisialisasi controller broadcast dengan integrasi WebSocket
+func NewBroadcastController(broadcastRepo *repositories.BroadcastRepository, chatRepo *repositories.ChatRepository, contactRepo *repositories.ContactRepository, ws *WSController) *BroadcastController {
+	return &BroadcastController{
+		BroadcastRepo: broadcastRepo,
+		ChatRepo:      chatRepo,
+		ContactRepo:   contactRepo,
+		WS:            ws,
+	}
+}
+
+// List mengambil semua daftar broadcast milik user.
+func (c *BroadcastController) List(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	lists, err := c.BroadcastRepo.GetByOwner(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil daftar broadcast", err)
+		return
+	}
+
+	res := make([]models.BroadcastListDTO, len(lists))
+	for i, l := range lists {
+		res[i] = toBroadcastListDTO(l)
+	}
+	utils.SuccessResponse(ctx, "Daftar broadcast", res)
+}
+
+// Create membuat daftar broadcast baru dari kontak user.
+func (c *BroadcastController) Create(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	var input models.CreateBroadcastListDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	recipients, err := c.validRecipients(userId, input.RecipientIDs)
+	if err != nil {
+		respondBroadcastError(ctx, "Gagal membuat daftar broadcast", err)
+		return
+	}
+
+	list, err := c.BroadcastRepo.Create(ctx.Request.Context(), userId, input.Name, recipients)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal membuat daftar broadcast", err)
+		return
+	}
+	utils.CreatedResponse(ctx, "Daftar broadcast dibuat", toBroadcastListDTO(*list))
+}
+
+// Get mengambil satu daftar broadcast milik user.
+func (c *BroadcastController) Get(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	list, err := c.BroadcastRepo.GetOwned(ctx.Request.Context(), ctx.Param("id"), userId)
+	if err != nil {
+		respondBroadcastError(ctx, "Gagal mengambil daftar broadcast", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Detail daftar broadcast", toBroadcastListDTO(*list))
+}
+
+// Update mengubah nama dan/atau mengganti seluruh penerima daftar broadcast.
+func (c *BroadcastController) Update(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	listId := ctx.Param("id")
+
+	var input models.UpdateBroadcastListDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+	if input.Name == nil && input.RecipientIDs == nil {
+		utils.BadRequest(ctx, "Tidak ada perubahan yang dikirim", nil)
+		return
+	}
+
+	if _, err := c.BroadcastRepo.GetOwned(ctx.Request.Context(), listId, userId); err != nil {
+		respondBroadcastError(ctx, "Gagal mengubah daftar broadcast", err)
+		return
+	}
+
+	if input.RecipientIDs != nil {
+		recipients, err := c.validRecipients(userId, input.RecipientIDs)
+		if err != nil {
+			respondBroadcastError(ctx, "Gagal mengubah daftar broadcast", err)
+			return
+		}
+		if err := c.BroadcastRepo.SetRecipients(ctx.Request.Context(), listId, recipients); err != nil {
+			utils.InternalError(ctx, "Gagal mengubah daftar broadcast", err)
+			return
+		}
+	}
+	if input.Name != nil {
+		if err := c.BroadcastRepo.Rename(ctx.Request.Context(), listId, *input.Name); err != nil {
+			utils.InternalError(ctx, "Gagal mengubah daftar broadcast", err)
+			return
+		}
+	}
+
+	list, err := c.BroadcastRepo.GetOwned(ctx.Request.Context(), listId, userId)
+	if err != nil {
+		respondBroadcastError(ctx, "Gagal mengubah daftar broadcast", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Daftar broadcast diperbarui", toBroadcastListDTO(*list))
+}
+
+// Delete menghapus daftar broadcast. Chat pribadi yang sudah terkirim tidak ikut terhapus.
+func (c *BroadcastController) Delete(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	listId := ctx.Param("id")
+
+	if _, err := c.BroadcastRepo.GetOwned(ctx.Request.Context(), listId, userId); err != nil {
+		respondBroadcastError(ctx, "Gagal menghapus daftar broadcast", err)
+		return
+	}
+	if err := c.BroadcastRepo.Delete(ctx.Request.Context(), listId); err != nil {
+		respondBroadcastError(ctx, "Gagal menghapus daftar broadcast", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Daftar broadcast dihapus", gin.H{"id": listId})
+}
+
+// Send mengirim pesan ke setiap penerima sebagai pesan pribadi terpisah (lewat jalur kirim normal),
+// sehingga balasan masuk ke chat 1-on-1 biasa. Seperti WhatsApp, penerima yang belum menyimpan
+// pengirim sebagai kontak dilewati. Hasil per penerima dikembalikan di response.
+func (c *BroadcastController) Send(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	var input models.BroadcastSendDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	list, err := c.BroadcastRepo.GetOwned(ctx.Request.Context(), ctx.Param("id"), userId)
+	if err != nil {
+		respondBroadcastError(ctx, "Gagal mengirim broadcast", err)
+		return
+	}
+
+	recipients := list.Recipients()
+	recipientIds := make([]string, len(recipients))
+	for i, r := range recipients {
+		recipientIds[i] = r.UserID
+	}
+	savedBy, err := c.ContactRepo.FilterSavedBy(userId, recipientIds)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengirim broadcast", err)
+		return
+	}
+
+	message := models.SendMessageDTO{
+		Content: input.Content,
+		Type:    input.Type,
+	}
+	result := models.BroadcastResultDTO{
+		ListID:  list.ID,
+		Results: make([]models.BroadcastDeliveryDTO, 0, len(recipientIds)),
+	}
+	for _, recipientId := range recipientIds {
+		delivery := models.BroadcastDeliveryDTO{UserID: recipientId}
+
+		if !savedBy[recipientId] {
+			delivery.Status = broadcastSkipped
+			delivery.Reason = "penerima belum menyimpan Anda sebagai kontak"
+			result.Skipped++
+			result.Results = append(result.Results, delivery)
+			continue
+		}
+		// Penerima yang memblokir / diblokir tidak menerima broadcast (alasan sengaja dibuat umum)
+		if c.ChatRepo.IsBlockedEitherWay(ctx.Request.Context(), userId, recipientId) {
+			delivery.Status = broadcastSkipped
+			delivery.Reason = "penerima tidak dapat menerima broadcast"
+			result.Skipped++
+			result.Results = append(result.Results, delivery)
+			continue
+		}
+
+		chat, err := c.ChatRepo.CreateOrGetDirectChat(ctx.Request.Context(), userId, recipientId)
+		if err != nil {
+			delivery.Status = broadcastFailed
+			delivery.Reason = utils.SafeErrorMessage(err)
+			result.Failed++
+			result.Results = append(result.Results, delivery)
+			continue
+		}
+		delivery.ChatID = chat.ID
+
+		msg, err := c.WS.SendMessage(ctx.Request.Context(), userId, chat.ID, message)
+		if err != nil {
+			delivery.Status = broadcastFailed
+			delivery.Reason = utils.SafeErrorMessage(err)
+			result.Failed++
+		} else {
+			delivery.Status = broadcastSent
+			delivery.MessageID = msg.ID
+			result.Sent++
+		}
+		result.Results = append(result.Results, delivery)
+	}
+
+	utils.SuccessResponse(ctx, "Broadcast diproses", result)
+}
+
+// validRecipients membuang duplikat & diri sendiri, lalu memastikan semua penerima ada di kontak user
+func (c *BroadcastController) validRecipients(userId string, recipientIds []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, id := range recipientIds {
+		if id == userId || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return nil, repositories.ErrBroadcastNoRecipients
+	}
+
+	contacts, err := c.ContactRepo.FilterContacts(userId, unique)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range unique {
+		if !contacts[id] {
+			return nil, repositories.ErrBroadcastRecipientNotContact
+		}
+	}
+	return unique, nil
+}
+
+// respondBroadcastError memetakan error daftar broadcast ke status HTTP yang sesuai
+func respondBroadcastError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrBroadcastRecipientNotContact),
+		errors.Is(err, repositories.ErrBroadcastNoRecipients):
+		utils.BadRequest(ctx, message, err)
+	case errors.Is(err, db.ErrNotFound):
+		utils.NotFound(ctx, "Daftar broadcast tidak ditemukan", err)
+	default:
+		utils.InternalError(ctx, message, err)
+	}
+}
+
+// toBroadcastListDTO mengubah model daftar broadcast menjadi DTO
+func toBroadcastListDTO(l db.BroadcastListModel) models.BroadcastListDTO {
+	dto := models.BroadcastListDTO{
+		ID:         l.ID,
+		Name:       l.Name,
+		Recipients: []models.BroadcastRecipientDTO{},
+		CreatedAt:  l.CreatedAt,
+		UpdatedAt:  l.UpdatedAt,
+	}
+	for _, r := range l.Recipients() {
+		u := r.User()
+		recipient := models.BroadcastRecipientDTO{
+			UserID: u.ID,
+			Name:   u.Name,
+		}
+		if v, ok := u.AvatarURL(); ok {
+			recipient.AvatarUrl = v
+		}
+		if v, ok := u.Color(); ok {
+			recipient.Color = v
+		}
+		dto.Recipients = append(dto.Recipients, recipient)
+	}
+	return dto
+}
@@ -2,8 +2,16 @@ package controllers
 
 import (
 	"chat-app-be/models"
+	"chat-app-be/prisma/db"
 	"chat-app-be/repositories"
 	"chat-app-be/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,13 +34,64 @@ func NewChatController(repo *repositories.ChatRepository, contactRepo *repositor
 
 // GetMessages mengambil list pesan berdasarkan ID chat room secara standar HTTP.
 func (c *ChatController) GetMessages(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
 	chatId := ctx.Param("id")
-	messages, err := c.ChatRepo.GetChatMessages(ctx.Request.Context(), chatId)
-	if err != nil {
+
+	// Hanya peserta aktif yang boleh membaca riwayat (anggota yang dikeluarkan kehilangan akses)
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), chatId, userId) {
+		respondChatError(ctx, "Gagal mengambil pesan", repositories.ErrNotParticipant)
+		return
+	}
+
+	// ?around=<messageId> mengambil potongan timeline di sekitar pesan tertentu (mis. hasil pencarian)
+	var messages []db.MessageModel
+	var err error
+	if around := ctx.Query("around"); around != "" {
+		_, limit, _ := utils.ParsePagination(ctx)
+		messages, err = c.ChatRepo.GetMessagesAround(ctx.Request.Context(), chatId, userId, around, limit)
+		if err != nil {
+			respondChatError(ctx, "Gagal mengambil pesan", err)
+			return
+		}
+	} else if messages, err = c.ChatRepo.GetChatMessages(ctx.Request.Context(), chatId, userId); err != nil {
 		utils.InternalError(ctx, "Gagal mengambil pesan", err)
 		return
 	}
-	utils.SuccessResponse(ctx, "Pesan ditemukan", messages)
+
+	res := make([]models.MessageDTO, len(messages))
+	for i, m := range messages {
+		res[i] = toMessageDTO(m)
+		res[i].Reactions = aggregateReactions(m.Reactions(), userId)
+		res[i].Thread = summarizeThread(m, m.ThreadFollowers(), userId)
+		if poll, ok := m.Poll(); ok {
+			res[i].Poll = toPollDTO(poll, userId)
+		}
+	}
+	utils.SuccessResponse(ctx, "Pesan ditemukan", res)
+}
+
+// SendMessage mengirim pesan lewat REST (alternatif WebSocket), termasuk balasan/quote.
+func (c *ChatController) SendMessage(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.SendMessageDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	msg, err := c.WS.SendMessage(ctx.Request.Context(), userId, chatId, input)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengirim pesan", err)
+		return
+	}
+	// Balasan thread dikirim dari panel thread, draft chat utama tidak disentuh
+	if input.ThreadRootID == "" {
+		c.WS.clearDraft(userId, chatId)
+	}
+
+	utils.CreatedResponse(ctx, "Pesan terkirim", toMessageDTO(*msg))
 }
 
 // GetUserChats mengambil daftar chat room user secara standar HTTP.
@@ -40,40 +99,42 @@ func (c *ChatController) GetMessages(ctx *gin.Context) {
 func (c *ChatController) GetUserChats(ctx *gin.Context) {
 	userId := ctx.GetString("userID")
 	
-    // 1. Get raw chats with relations
-    chats, err := c.ChatRepo.GetUserChats(ctx.Request.Context(), userId)
+    // 1. Get raw chats with relations (chat arsip hanya jika ?archived=true)
+    archived := ctx.Query("archived") == "true"
+    memberships, err := c.ChatRepo.GetUserChats(ctx.Request.Context(), userId, archived)
 	if err != nil {
 		utils.InternalError(ctx, "Gagal mengambil daftar chat", err)
 		return
 	}
 
     // 2. Get user's contacts to resolve Aliases
-    contacts, _ := c.ContactRepo.GetContacts(userId)
-    contactMap := make(map[string]string) // UserID -> Alias
-    for _, contact := range contacts {
-        if contact.Alias != "" {
-            contactMap[contact.ContactID] = contact.Alias
-        }
-    }
-
-	res := make([]models.ChatDTO, len(chats))
-	for i, chat := range chats {
+    contactMap := contactAliasMap(c.ContactRepo, userId)
+
+	// 3. Hitung unread per chat dari cursor baca user
+	unreadCounts, err := c.ChatRepo.GetUnreadCounts(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil daftar chat", err)
+		return
+	}
+	mentionCounts, err := c.ChatRepo.GetUnreadMentionCounts(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil daftar chat", err)
+		return
+	}
+
+	now := time.Now()
+	res := make([]models.ChatDTO, len(memberships))
+	lastActivity := make(map[string]time.Time, len(memberships))
+	pinnedAt := make(map[string]time.Time, len(memberships))
+	for i, p := range memberships {
+		chat := *p.Chat()
 		var lastMsg interface{}
 		messages := chat.Messages()
-        
-        // Calculate Unread Count
-        // Because repo filters for unread & not sender, we can just count the length
-        unreadCount := len(messages)
-        
-        // Find actual last message (repo fetches unread ones + last message separately)
-        // Since our repo query is complex, let's simplify: GetUserChats repo fetches LAST MESSAGE and UNREAD MESSAGES.
-        // Actually, the previous repo change fetches unread messages. We might miss the last message if it WAS read.
-        // Let's rely on the separate 'lastMessage' relation we added in repo or fix repo to return both.
-        // For now, let's assume valid last message is at index 0 if list not empty.
-        
+
+		// Repo hanya mengambil 1 pesan terbaru sebagai last message
 		if len(messages) > 0 {
-            // Note: This might be an unread message, which IS a valid last message.
 			m := messages[0]
+			lastActivity[chat.ID] = m.Timestamp
 			sender := m.Sender()
 			lastMsg = gin.H{
 				"id":        m.ID,
@@ -83,46 +144,319 @@ func (c *ChatController) GetUserChats(ctx *gin.Context) {
 				"sender":    sender.Name,
 				"type":      m.Type,
 				"status":    m.Status,
+				"preview":   messagePreview(m),
 			}
 		}
 
 		// Resolve Name
-		name := "Unknown"
-		if chat.IsGroup {
-			if n, ok := chat.Name(); ok {
-				name = n
-			}
-		} else {
-            // Direct Chat: Find the OTHER participant
-            participants := chat.Participants()
-            for _, p := range participants {
-                if p.UserID != userId {
-                    // This is the other person
-                    otherUser := p.User()
-                    
-                    // Check if we have an alias for them
-                    if alias, ok := contactMap[p.UserID]; ok {
-                        name = alias
-                    } else {
-                        name = otherUser.Name
-                    }
-                    break
-                }
-            }
-        }
+		name := resolveChatName(chat, userId, contactMap)
 
 		res[i] = models.ChatDTO{
 			ID:          chat.ID,
 			Name:        name,
 			IsGroup:     chat.IsGroup,
 			LastMessage: lastMsg,
-			UnreadCount: unreadCount, 
+			UnreadCount: unreadCounts[chat.ID],
+
+			UnreadMentionCount: mentionCounts[chat.ID],
+
+			IsMuted:      repositories.IsMuted(p, now),
+			IsArchived:   p.IsArchived,
+			KeepArchived: p.KeepArchived,
+			IsPinned:     p.IsPinned,
+
+			DisappearingSeconds: chat.DisappearingSeconds,
+
+			Draft: toDraftDTO(p),
+		}
+		if pins := chat.Pins(); len(pins) > 0 {
+			res[i].LatestPin = toPinnedMessageDTO(pins[0])
+		}
+		if until, ok := p.MutedUntil(); ok && res[i].IsMuted && !p.MutedForever {
+			res[i].MutedUntil = &until
+		}
+		if v, ok := p.PinnedAt(); ok && p.IsPinned {
+			pinnedAt[chat.ID] = v
 		}
 	}
 
+	// 4. Chat yang di-pin di atas (pin terbaru dulu), sisanya berdasarkan aktivitas terakhir
+	sort.SliceStable(res, func(a, b int) bool {
+		if res[a].IsPinned != res[b].IsPinned {
+			return res[a].IsPinned
+		}
+		if res[a].IsPinned {
+			return pinnedAt[res[a].ID].After(pinnedAt[res[b].ID])
+		}
+		return lastActivity[res[a].ID].After(lastActivity[res[b].ID])
+	})
+
 	utils.SuccessResponse(ctx, "Daftar chat berhasil diambil", res)
 }
 
+// UpdatePreferences mengubah preferensi chat milik user: mute, arsip, dan pin ke atas.
+func (c *ChatController) UpdatePreferences(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.ChatPreferencesDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	participant, err := c.ChatRepo.GetParticipant(ctx.Request.Context(), chatId, userId)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengubah preferensi chat", err)
+		return
+	}
+
+	var params []db.ParticipantSetParam
+	if input.Mute != nil {
+		params = append(params, muteParams(*input.Mute, time.Now())...)
+	}
+	if input.Archived != nil {
+		params = append(params, db.Participant.IsArchived.Set(*input.Archived))
+	}
+	if input.KeepArchived != nil {
+		params = append(params, db.Participant.KeepArchived.Set(*input.KeepArchived))
+	}
+	if input.Pinned != nil {
+		// Batas jumlah chat yang di-pin hanya berlaku saat menambah pin baru
+		if *input.Pinned && !participant.IsPinned {
+			pinned, err := c.ChatRepo.CountPinnedChats(ctx.Request.Context(), userId)
+			if err != nil {
+				utils.InternalError(ctx, "Gagal mengubah preferensi chat", err)
+				return
+			}
+			if pinned >= chatPinLimit() {
+				respondChatError(ctx, "Gagal mengubah preferensi chat", repositories.ErrChatPinLimit)
+				return
+			}
+			params = append(params, db.Participant.PinnedAt.Set(time.Now()))
+		}
+		params = append(params, db.Participant.IsPinned.Set(*input.Pinned))
+	}
+
+	updated, err := c.ChatRepo.UpdateChatPreferences(ctx.Request.Context(), chatId, userId, params...)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengubah preferensi chat", err)
+		return
+	}
+
+	now := time.Now()
+	res := gin.H{
+		"chatId":       chatId,
+		"isMuted":      repositories.IsMuted(*updated, now),
+		"isArchived":   updated.IsArchived,
+		"keepArchived": updated.KeepArchived,
+		"isPinned":     updated.IsPinned,
+	}
+	if until, ok := updated.MutedUntil(); ok && !updated.MutedForever && until.After(now) {
+		res["mutedUntil"] = until
+	}
+	utils.SuccessResponse(ctx, "Preferensi chat diperbarui", res)
+}
+
+// disappearingDurations memetakan pilihan timer pesan sementara ke detik beserta labelnya
+var disappearingDurations = map[string]struct {
+	seconds int
+	label   string
+}{
+	"off": {0, ""},
+	"24h": {24 * 60 * 60, "24 jam"},
+	"7d":  {7 * 24 * 60 * 60, "7 hari"},
+	"90d": {90 * 24 * 60 * 60, "90 hari"},
+}
+
+// SetDisappearing mengatur timer pesan sementara chat (grup: khusus admin).
+// Berlaku untuk pesan baru, lalu diumumkan lewat pesan INFO.
+func (c *ChatController) SetDisappearing(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+
+	var input models.DisappearingDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	participant, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionDisappearing)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengatur pesan sementara", err)
+		return
+	}
+
+	duration := disappearingDurations[input.Duration]
+	if participant.Chat().DisappearingSeconds == duration.seconds {
+		utils.SuccessResponse(ctx, "Timer pesan sementara tidak berubah", gin.H{"chatId": chatId, "disappearingSeconds": duration.seconds})
+		return
+	}
+
+	if _, err := c.ChatRepo.SetDisappearingTimer(ctx.Request.Context(), chatId, duration.seconds); err != nil {
+		utils.InternalError(ctx, "Gagal mengatur pesan sementara", err)
+		return
+	}
+
+	info := userName + " menonaktifkan pesan sementara"
+	if duration.seconds > 0 {
+		info = userName + " mengaktifkan pesan sementara. Pesan baru akan hilang setelah " + duration.label
+	}
+	c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	c.WS.BroadcastEvent(chatId, WSMessage{
+		Type: "disappearing_updated",
+		Data: gin.H{"disappearingSeconds": duration.seconds},
+	})
+
+	utils.SuccessResponse(ctx, "Timer pesan sementara diperbarui", gin.H{"chatId": chatId, "disappearingSeconds": duration.seconds})
+}
+
+// GetNextMention mengambil pesan tertua yang me-mention user dan belum dibaca.
+// Query opsional: ?after=<messageId> untuk lompat ke mention setelah pesan tersebut.
+func (c *ChatController) GetNextMention(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	msg, err := c.ChatRepo.GetNextUnreadMention(ctx.Request.Context(), chatId, userId, ctx.Query("after"))
+	if err != nil {
+		respondChatError(ctx, "Gagal mengambil mention", err)
+		return
+	}
+	if msg == nil {
+		utils.SuccessResponse(ctx, "Tidak ada mention yang belum dibaca", nil)
+		return
+	}
+
+	dto := toMessageDTO(*msg)
+	dto.Reactions = aggregateReactions(msg.Reactions(), userId)
+	utils.SuccessResponse(ctx, "Mention ditemukan", dto)
+}
+
+// CreatePoll membuat polling di grup; disebarkan ke anggota sebagai pesan bertipe POLL.
+func (c *ChatController) CreatePoll(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.CreatePollDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+	if input.ClosesAt != nil && !input.ClosesAt.After(time.Now()) {
+		utils.BadRequest(ctx, "Waktu tutup polling harus di masa depan", nil)
+		return
+	}
+
+	msg, err := c.WS.CreatePoll(ctx.Request.Context(), userId, chatId, input)
+	if err != nil {
+		respondChatError(ctx, "Gagal membuat polling", err)
+		return
+	}
+
+	dto := toMessageDTO(*msg)
+	if poll, ok := msg.Poll(); ok {
+		dto.Poll = toPollDTO(poll, userId)
+	}
+	utils.CreatedResponse(ctx, "Polling dibuat", dto)
+}
+
+// StartLiveLocation mulai membagikan lokasi terkini selama 15 menit, 1 jam, atau 8 jam.
+// Titik berikutnya dikirim lewat WebSocket ("live_location_update").
+func (c *ChatController) StartLiveLocation(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.StartLiveLocationDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	msg, session, err := c.WS.StartLiveLocation(ctx.Request.Context(), userId, chatId, input)
+	if err != nil {
+		respondChatError(ctx, "Gagal membagikan lokasi terkini", err)
+		return
+	}
+
+	dto := toMessageDTO(*msg)
+	live := toLiveLocationDTO(*session, time.Now())
+	dto.LiveLocation = &live
+	utils.CreatedResponse(ctx, "Lokasi terkini dibagikan", dto)
+}
+
+// StopLiveLocation menghentikan lokasi terkini yang sedang dibagikan user di chat
+func (c *ChatController) StopLiveLocation(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if _, err := c.WS.StopLiveLocation(ctx.Request.Context(), userId, chatId); err != nil {
+		respondChatError(ctx, "Gagal menghentikan lokasi terkini", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Berbagi lokasi terkini dihentikan", nil)
+}
+
+// GetLiveLocations mengambil posisi terakhir semua lokasi terkini yang masih aktif di chat
+// (untuk peserta yang baru membuka chat setelah sesi dimulai)
+func (c *ChatController) GetLiveLocations(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), chatId, userId) {
+		respondChatError(ctx, "Gagal mengambil lokasi terkini", repositories.ErrNotParticipant)
+		return
+	}
+
+	now := time.Now()
+	sessions, err := c.ChatRepo.GetChatLiveLocations(ctx.Request.Context(), chatId, userId, now)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil lokasi terkini", err)
+		return
+	}
+
+	res := make([]models.LiveLocationDTO, len(sessions))
+	for i, s := range sessions {
+		res[i] = toLiveLocationDTO(s, now)
+	}
+	utils.SuccessResponse(ctx, "Lokasi terkini ditemukan", res)
+}
+
+// muteParams menerjemahkan pilihan durasi mute ke field Participant
+func muteParams(option string, now time.Time) []db.ParticipantSetParam {
+	switch option {
+	case "8h":
+		return []db.ParticipantSetParam{
+			db.Participant.MutedUntil.Set(now.Add(8 * time.Hour)),
+			db.Participant.MutedForever.Set(false),
+		}
+	case "1w":
+		return []db.ParticipantSetParam{
+			db.Participant.MutedUntil.Set(now.Add(7 * 24 * time.Hour)),
+			db.Participant.MutedForever.Set(false),
+		}
+	case "forever":
+		return []db.ParticipantSetParam{
+			db.Participant.MutedUntil.SetOptional(nil),
+			db.Participant.MutedForever.Set(true),
+		}
+	default: // off
+		return []db.ParticipantSetParam{
+			db.Participant.MutedUntil.SetOptional(nil),
+			db.Participant.MutedForever.Set(false),
+		}
+	}
+}
+
+// chatPinLimit membaca batas chat yang boleh di-pin per user dari env (default 3)
+func chatPinLimit() int {
+	limit, _ := strconv.Atoi(os.Getenv("PINNED_CHAT_LIMIT"))
+	if limit <= 0 {
+		limit = 3
+	}
+	return limit
+}
+
 // CreateGroup membuat grup baru dan memberitahu semua anggota secara real-time.
 // CreateGroup membuat grup baru dan memberitahu semua anggota secara real-time.
 func (c *ChatController) CreateGroup(ctx *gin.Context) {
@@ -137,10 +471,21 @@ func (c *ChatController) CreateGroup(ctx *gin.Context) {
 		return
 	}
 
+	// User yang memblokir pembuat grup tidak bisa ditambahkan olehnya
+	blocked, err := c.ChatRepo.BlockedByAny(ctx.Request.Context(), userId, dto.UserIDs)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal membuat grup", err)
+		return
+	}
+	if blocked {
+		respondChatError(ctx, "Gagal membuat grup", repositories.ErrUserBlocked)
+		return
+	}
+
 	// Tambahkan creator ke daftar member
 	allMembers := append(dto.UserIDs, userId)
 
-	chat, err := c.ChatRepo.CreateGroupChat(ctx.Request.Context(), dto.Name, allMembers)
+	chat, err := c.ChatRepo.CreateGroupChat(ctx.Request.Context(), dto.Name, userId, allMembers)
 	if err != nil {
 		utils.InternalError(ctx, "Gagal membuat grup", err)
 		return
@@ -168,9 +513,533 @@ func (c *ChatController) CreateDirectChat(ctx *gin.Context) {
 	// Create or get existing direct chat
 	chat, err := c.ChatRepo.CreateOrGetDirectChat(ctx.Request.Context(), userId, dto.ContactUserId)
 	if err != nil {
-		utils.InternalError(ctx, "Failed to create/get direct chat", err)
+		respondChatError(ctx, "Failed to create/get direct chat", err)
 		return
 	}
 
 	utils.SuccessResponse(ctx, "Direct chat ready", chat)
 }
+
+// PinMessage menyematkan pesan di chat (admin grup / peserta chat direct).
+func (c *ChatController) PinMessage(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+
+	var input models.PinMessageDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionPin); err != nil {
+		respondChatError(ctx, "Gagal menyematkan pesan", err)
+		return
+	}
+
+	// Pesan harus ada di chat ini dan belum dihapus. Pesan yang di-drop karena blokir tidak bisa
+	// disematkan, termasuk oleh pengirimnya, karena pin terlihat oleh semua peserta chat.
+	msg, err := c.ChatRepo.FindMessageInChat(ctx.Request.Context(), chatId, input.MessageID)
+	if err != nil {
+		respondChatError(ctx, "Pesan tidak valid", err)
+		return
+	}
+	if msg.DroppedByBlock {
+		respondChatError(ctx, "Pesan tidak valid", repositories.ErrMessageNotInChat)
+		return
+	}
+	if msg.IsDeleted {
+		respondChatError(ctx, "Pesan tidak valid", repositories.ErrMessageDeleted)
+		return
+	}
+
+	limit := pinLimit()
+	_, created, err := c.ChatRepo.PinMessage(ctx.Request.Context(), chatId, input.MessageID, userId, limit)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPinLimit) {
+			utils.BadRequest(ctx, fmt.Sprintf("Maksimal %d pesan yang bisa disematkan", limit), err)
+			return
+		}
+		respondChatError(ctx, "Gagal menyematkan pesan", err)
+		return
+	}
+
+	// Pesan sistem + event real-time hanya jika benar-benar ada pin baru
+	if created {
+		c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, userName+" menyematkan pesan")
+	}
+	pins := c.broadcastPins(ctx, chatId)
+
+	utils.SuccessResponse(ctx, "Pesan disematkan", pins)
+}
+
+// UnpinMessage melepas pesan yang tersemat di chat.
+func (c *ChatController) UnpinMessage(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+	messageId := ctx.Param("messageId")
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionPin); err != nil {
+		respondChatError(ctx, "Gagal melepas pesan", err)
+		return
+	}
+
+	if err := c.ChatRepo.UnpinMessage(ctx.Request.Context(), chatId, messageId); err != nil {
+		respondChatError(ctx, "Gagal melepas pesan", err)
+		return
+	}
+	pins := c.broadcastPins(ctx, chatId)
+
+	utils.SuccessResponse(ctx, "Pesan dilepas dari sematan", pins)
+}
+
+// GetPins mengambil daftar pesan yang disematkan di chat.
+func (c *ChatController) GetPins(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), chatId, userId) {
+		respondChatError(ctx, "Gagal mengambil pesan tersemat", repositories.ErrNotParticipant)
+		return
+	}
+
+	pins, err := c.ChatRepo.GetPinnedMessages(ctx.Request.Context(), chatId, userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil pesan tersemat", err)
+		return
+	}
+
+	res := make([]models.PinnedMessageDTO, len(pins))
+	for i, p := range pins {
+		res[i] = *toPinnedMessageDTO(p)
+	}
+	utils.SuccessResponse(ctx, "Pesan tersemat ditemukan", res)
+}
+
+// broadcastPins mengirim event "pinned_updated" berisi daftar pin terbaru ke peserta chat
+func (c *ChatController) broadcastPins(ctx *gin.Context, chatId string) []models.PinnedMessageDTO {
+	// Dikirim ke semua peserta, jadi hanya pin yang terlihat oleh semua orang (viewer kosong)
+	pins, _ := c.ChatRepo.GetPinnedMessages(ctx.Request.Context(), chatId, "")
+	res := make([]models.PinnedMessageDTO, len(pins))
+	for i, p := range pins {
+		res[i] = *toPinnedMessageDTO(p)
+	}
+
+	c.WS.BroadcastEvent(chatId, WSMessage{
+		Type: "pinned_updated",
+		Data: res,
+	})
+	return res
+}
+
+// contactAliasMap mengambil kontak user untuk resolve alias (UserID -> Alias)
+func contactAliasMap(contactRepo *repositories.ContactRepository, userId string) map[string]string {
+	contacts, _ := contactRepo.GetContacts(userId)
+	contactMap := make(map[string]string)
+	for _, contact := range contacts {
+		if contact.Alias != "" {
+			contactMap[contact.ContactID] = contact.Alias
+		}
+	}
+	return contactMap
+}
+
+// resolveChatName menentukan nama chat dari sudut pandang user:
+// nama grup, atau nama/alias lawan bicara untuk chat direct (participants harus di-fetch beserta user-nya)
+func resolveChatName(chat db.ChatModel, userId string, aliases map[string]string) string {
+	name := "Unknown"
+	if chat.IsGroup {
+		if n, ok := chat.Name(); ok {
+			name = n
+		}
+		return name
+	}
+
+	// Direct Chat: Find the OTHER participant
+	for _, p := range chat.Participants() {
+		if p.UserID != userId {
+			// Check if we have an alias for them
+			if alias, ok := aliases[p.UserID]; ok {
+				return alias
+			}
+			return p.User().Name
+		}
+	}
+	return name
+}
+
+// resolveUserName mengembalikan alias kontak jika ada, selain itu nama asli user
+func resolveUserName(user *db.UserModel, aliases map[string]string) string {
+	if alias, ok := aliases[user.ID]; ok {
+		return alias
+	}
+	return user.Name
+}
+
+// pinLimit membaca batas pin per chat dari env (default 3)
+func pinLimit() int {
+	limit, _ := strconv.Atoi(os.Getenv("PIN_LIMIT_PER_CHAT"))
+	if limit <= 0 {
+		limit = 3
+	}
+	return limit
+}
+
+// toPinnedMessageDTO mengubah model pin menjadi DTO (pesan ikut disertakan jika di-fetch)
+func toPinnedMessageDTO(p db.PinnedMessageModel) *models.PinnedMessageDTO {
+	dto := &models.PinnedMessageDTO{
+		ChatID:     p.ChatID,
+		MessageID:  p.MessageID,
+		PinnedByID: p.PinnedByID,
+		PinnedAt:   p.PinnedAt,
+	}
+	if user := p.RelationsPinnedMessage.PinnedBy; user != nil {
+		dto.PinnedByName = user.Name
+	}
+	if msg := p.RelationsPinnedMessage.Message; msg != nil {
+		m := toMessageDTO(*msg)
+		dto.Message = &m
+	}
+	return dto
+}
+
+// toMessageDTO mengubah model Prisma menjadi MessageDTO (termasuk preview reply jika di-fetch)
+func toMessageDTO(m db.MessageModel) models.MessageDTO {
+	dto := models.MessageDTO{
+		ID:        m.ID,
+		SenderID:  m.SenderID,
+		ChatID:    m.ChatID,
+		Content:   m.Content,
+		Timestamp: m.Timestamp,
+		Type:      string(m.Type),
+		Status:    string(m.Status),
+		IsDeleted: m.IsDeleted,
+
+		IsForwarded:         m.IsForwarded,
+		ForwardCount:        m.ForwardCount,
+		FrequentlyForwarded: m.ForwardCount >= frequentlyForwardedThreshold,
+	}
+	if v, ok := m.EditedAt(); ok {
+		dto.EditedAt = &v
+	}
+	if v, ok := m.ReplyToID(); ok {
+		dto.ReplyToID = v
+	}
+	if v, ok := m.ExpiresAt(); ok {
+		dto.ExpiresAt = &v
+	}
+	if v, ok := m.ThreadRootID(); ok {
+		dto.ThreadRootID = v
+	}
+	if poll, ok := m.Poll(); ok {
+		dto.Poll = toPollDTO(poll, "")
+	}
+	dto.Location, dto.Contact, dto.Audio = decodePayload(m)
+	if live, ok := m.LiveLocation(); ok && !m.IsDeleted {
+		liveDTO := toLiveLocationDTO(*live, time.Now())
+		dto.LiveLocation = &liveDTO
+	}
+	dto.MentionsAll = m.MentionsAll
+	if lp, ok := m.LinkPreview(); ok && !lp.Failed {
+		dto.LinkPreview = toLinkPreviewDTO(lp)
+	}
+	// Relasi mentions tidak selalu di-fetch (mis. hasil CreateMessage), jadi dibaca langsung agar tidak panic
+	for _, mention := range m.RelationsMessage.Mentions {
+		dto.Mentions = append(dto.Mentions, mention.UserID)
+	}
+	if reply, ok := m.ReplyTo(); ok {
+		dto.ReplyTo = toReplyPreview(reply)
+	}
+	return dto
+}
+
+// frequentlyForwardedThreshold batas jumlah forward berantai sebelum pesan ditandai "sering diteruskan"
+const frequentlyForwardedThreshold = 5
+
+// replySnippetLength batas karakter potongan isi pesan pada preview reply
+const replySnippetLength = 100
+
+// toReplyPreview membuat ringkasan pesan yang dikutip
+func toReplyPreview(m *db.MessageModel) *models.ReplyPreviewDTO {
+	preview := &models.ReplyPreviewDTO{
+		ID:        m.ID,
+		SenderID:  m.SenderID,
+		Type:      string(m.Type),
+		IsDeleted: m.IsDeleted,
+	}
+	if sender := m.RelationsMessage.Sender; sender != nil {
+		preview.SenderName = sender.Name
+	}
+
+	// Isi pesan yang sudah dihapus tidak boleh bocor lewat preview
+	if !m.IsDeleted {
+		preview.Snippet = truncateRunes(messagePreview(*m), replySnippetLength)
+	}
+	return preview
+}
+
+// decodePayload membaca kolom payload ke struct yang sesuai tipe pesan (nil jika bukan tipe terstruktur)
+func decodePayload(m db.MessageModel) (location *models.LocationPayload, contact *models.ContactPayload, audio *models.AudioPayload) {
+	// Isi pesan yang sudah dihapus tidak boleh bocor lewat payload
+	raw, ok := m.Payload()
+	if !ok || m.IsDeleted {
+		return nil, nil, nil
+	}
+	var target interface{}
+	switch m.Type {
+	case db.MessageTypeLocation:
+		location = &models.LocationPayload{}
+		target = location
+	case db.MessageTypeContact:
+		contact = &models.ContactPayload{}
+		target = contact
+	case db.MessageTypeAudio:
+		audio = &models.AudioPayload{}
+		target = audio
+	default:
+		return nil, nil, nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return nil, nil, nil
+	}
+	return location, contact, audio
+}
+
+// messagePreview membuat teks ringkas pesan untuk daftar chat, notifikasi, dan preview reply.
+// Tipe terstruktur diringkas dari payload-nya, tipe lain memakai content apa adanya.
+func messagePreview(m db.MessageModel) string {
+	location, contact, audio := decodePayload(m)
+	switch {
+	case location != nil:
+		if location.Label != "" {
+			return "📍 " + location.Label
+		}
+		return "📍 Lokasi"
+	case contact != nil:
+		return "👤 " + contact.Name
+	case audio != nil:
+		seconds := audio.DurationMs / 1000
+		return fmt.Sprintf("🎤 Pesan suara (%d:%02d)", seconds/60, seconds%60)
+	}
+	return m.Content
+}
+
+// truncateRunes memotong teks berdasarkan jumlah karakter (aman untuk emoji/unicode)
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
+
+// aggregateReactions mengelompokkan reaksi per emoji (urutan sesuai emoji pertama kali dipakai)
+func aggregateReactions(reactions []db.MessageReactionModel, viewerID string) []models.ReactionDTO {
+	var res []models.ReactionDTO
+	index := make(map[string]int)
+	for _, r := range reactions {
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(res)
+			index[r.Emoji] = i
+			res = append(res, models.ReactionDTO{Emoji: r.Emoji})
+		}
+		res[i].Count++
+		if r.UserID == viewerID {
+			res[i].ReactedByMe = true
+		}
+	}
+	return res
+}
+
+// summarizeThread membuat ringkasan thread pada pesan root dari sudut pandang viewer.
+// Mengembalikan nil jika pesan belum punya balasan thread.
+func summarizeThread(root db.MessageModel, followers []db.ThreadFollowerModel, viewerID string) *models.ThreadSummaryDTO {
+	if root.ThreadReplyCount == 0 {
+		return nil
+	}
+
+	summary := &models.ThreadSummaryDTO{
+		ReplyCount:   root.ThreadReplyCount,
+		Participants: []models.ThreadParticipantDTO{},
+	}
+	if v, ok := root.ThreadLastReplyAt(); ok {
+		summary.LastReplyAt = &v
+	}
+	for _, f := range followers {
+		if f.UserID == viewerID {
+			summary.IsFollowing = true
+			summary.UnreadCount = f.UnreadCount
+		}
+		if !f.HasReplied || f.RelationsThreadFollower.User == nil {
+			continue
+		}
+		participant := models.ThreadParticipantDTO{
+			UserID: f.UserID,
+			Name:   f.User().Name,
+		}
+		if v, ok := f.User().AvatarURL(); ok {
+			participant.AvatarUrl = v
+		}
+		summary.Participants = append(summary.Participants, participant)
+	}
+	return summary
+}
+
+// toPollDTO mengagregasi suara polling per opsi dari sudut pandang viewer
+func toPollDTO(p *db.PollModel, viewerID string) *models.PollDTO {
+	dto := &models.PollDTO{
+		ID:            p.ID,
+		Question:      p.Question,
+		AllowMultiple: p.AllowMultiple,
+		IsAnonymous:   p.IsAnonymous,
+		IsClosed:      repositories.IsPollClosed(*p, time.Now()),
+		Options:       []models.PollOptionDTO{},
+	}
+	if v, ok := p.ClosesAt(); ok {
+		dto.ClosesAt = &v
+	}
+
+	voters := make(map[string]bool)
+	for _, o := range p.Options() {
+		option := models.PollOptionDTO{
+			ID:        o.ID,
+			Text:      o.Text,
+			VoteCount: len(o.Votes()),
+		}
+		for _, v := range o.Votes() {
+			voters[v.UserID] = true
+			if v.UserID == viewerID {
+				option.VotedByMe = true
+			}
+		}
+		dto.Options = append(dto.Options, option)
+	}
+	dto.TotalVoters = len(voters)
+	return dto
+}
+
+// SaveDraft menyimpan draft pesan user di chat dan menyinkronkannya ke perangkat lain via WebSocket.
+// content kosong tanpa replyToId menghapus draft.
+func (c *ChatController) SaveDraft(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.SaveDraftDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	draft, err := c.WS.SaveDraft(ctx.Request.Context(), userId, chatId, input, nil)
+	if err != nil {
+		respondChatError(ctx, "Gagal menyimpan draft", err)
+		return
+	}
+	if draft == nil {
+		utils.SuccessResponse(ctx, "Draft dihapus", gin.H{"chatId": chatId, "draft": nil})
+		return
+	}
+	utils.SuccessResponse(ctx, "Draft disimpan", gin.H{"chatId": chatId, "draft": draft})
+}
+
+// toDraftDTO mengambil draft dari data peserta; nil jika user tidak punya draft di chat ini
+func toDraftDTO(p db.ParticipantModel) *models.DraftDTO {
+	updatedAt, ok := p.DraftUpdatedAt()
+	if !ok {
+		return nil
+	}
+	draft := &models.DraftDTO{UpdatedAt: updatedAt}
+	if v, ok := p.DraftContent(); ok {
+		draft.Content = v
+	}
+	if v, ok := p.DraftReplyToID(); ok {
+		draft.ReplyToID = v
+	}
+	return draft
+}
+
+// toLiveLocationDTO mengubah sesi lokasi terkini menjadi DTO. Nama user diisi jika relasi User dimuat.
+func toLiveLocationDTO(s db.LiveLocationModel, now time.Time) models.LiveLocationDTO {
+	dto := models.LiveLocationDTO{
+		MessageID: s.MessageID,
+		ChatID:    s.ChatID,
+		UserID:    s.UserID,
+		Latitude:  s.Latitude,
+		Longitude: s.Longitude,
+		UpdatedAt: s.LastPointAt,
+		StartedAt: s.StartedAt,
+		ExpiresAt: s.ExpiresAt,
+	}
+	if v, ok := s.Accuracy(); ok {
+		dto.Accuracy = &v
+	}
+	if v, ok := s.Heading(); ok {
+		dto.Heading = &v
+	}
+	if v, ok := s.EndedAt(); ok {
+		dto.EndedAt = &v
+	}
+	dto.IsActive = dto.EndedAt == nil && s.ExpiresAt.After(now)
+	if user := s.RelationsLiveLocation.User; user != nil {
+		dto.UserName = user.Name
+	}
+	return dto
+}
+
+// toLinkPreviewDTO mengubah cache preview link menjadi DTO
+func toLinkPreviewDTO(lp *db.LinkPreviewModel) *models.LinkPreviewDTO {
+	dto := &models.LinkPreviewDTO{URL: lp.URL}
+	if v, ok := lp.Title(); ok {
+		dto.Title = v
+	}
+	if v, ok := lp.Description(); ok {
+		dto.Description = v
+	}
+	if v, ok := lp.ImageURL(); ok {
+		dto.ImageUrl = v
+	}
+	if v, ok := lp.SiteName(); ok {
+		dto.SiteName = v
+	}
+	return dto
+}
+
+// respondChatError memetakan error bisnis chat ke status HTTP yang sesuai
+func respondChatError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotParticipant):
+		utils.Forbidden(ctx, "Anda bukan peserta chat ini", err)
+	case errors.Is(err, repositories.ErrNotChatAdmin):
+		utils.Forbidden(ctx, "Hanya admin grup yang boleh melakukan aksi ini", err)
+	case errors.Is(err, repositories.ErrAnnouncementOnly):
+		utils.Forbidden(ctx, "Hanya admin yang boleh mengirim pesan di grup ini", err)
+	case errors.Is(err, repositories.ErrPollAnonymous):
+		utils.Forbidden(ctx, "Polling ini anonim", err)
+	case errors.Is(err, repositories.ErrUserBlocked):
+		utils.Forbidden(ctx, "Tidak dapat berinteraksi dengan user ini", err)
+	case errors.Is(err, repositories.ErrBlockingUser):
+		utils.Forbidden(ctx, "Buka blokir user ini terlebih dahulu", err)
+	case errors.Is(err, db.ErrNotFound):
+		utils.NotFound(ctx, "Data tidak ditemukan", err)
+	case errors.Is(err, repositories.ErrNoLiveLocation):
+		utils.NotFound(ctx, message, err)
+	case errors.Is(err, repositories.ErrMessageNotInChat),
+		errors.Is(err, repositories.ErrMessageDeleted),
+		errors.Is(err, repositories.ErrNotForwardable),
+		errors.Is(err, repositories.ErrPinLimit),
+		errors.Is(err, repositories.ErrChatPinLimit),
+		errors.Is(err, repositories.ErrInvalidThread),
+		errors.Is(err, repositories.ErrNotPoll),
+		errors.Is(err, repositories.ErrPollClosed),
+		errors.Is(err, repositories.ErrInvalidPollVote),
+		errors.Is(err, repositories.ErrEmptyContent),
+		errors.Is(err, repositories.ErrPayloadRequired),
+		errors.Is(err, repositories.ErrForeignMediaURL),
+		errors.Is(err, repositories.ErrDraftTooLong),
+		errors.Is(err, repositories.ErrNotGroup):
+		utils.BadRequest(ctx, message, err)
+	default:
+		utils.InternalError(ctx, message, err)
+	}
+}
@@ -0,0 +1,420 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupController mengatur manajemen grup: anggota, role, izin, keluar grup, dan info grup
+type GroupController struct {
+	ChatRepo *repositories.ChatRepository
+	WS       *WSController
+}
+
+// NewGroupController inisialisasi controller grup dengan integrasi WebSocket
+func NewGroupController(repo *repositories.ChatRepository, ws *WSController) *GroupController {
+	return &GroupController{
+		ChatRepo: repo,
+		WS:       ws,
+	}
+}
+
+// AddMembers menambahkan anggota baru ke grup (khusus admin).
+func (c *GroupController) AddMembers(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+
+	var input models.AddMembersDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	actor, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionAddMembers)
+	if err != nil {
+		respondChatError(ctx, "Gagal menambah anggota", err)
+		return
+	}
+	chat := actor.Chat()
+
+	// User yang memblokir actor tidak bisa ditambahkan olehnya
+	blocked, err := c.ChatRepo.BlockedByAny(ctx.Request.Context(), userId, input.UserIDs)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal menambah anggota", err)
+		return
+	}
+	if blocked {
+		respondChatError(ctx, "Gagal menambah anggota", repositories.ErrUserBlocked)
+		return
+	}
+
+	added, err := c.ChatRepo.AddParticipants(ctx.Request.Context(), chatId, input.UserIDs)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal menambah anggota", err)
+		return
+	}
+	if len(added) == 0 {
+		utils.SuccessResponse(ctx, "Semua user sudah menjadi anggota", gin.H{"added": added})
+		return
+	}
+
+	// Pesan INFO + notifikasi ke grup dan ke anggota baru
+	info := userName + " menambahkan " + c.joinNames(ctx, added)
+	c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	c.WS.NotifyGroup(chatId, userId, info, gin.H{
+		"action":  "members_added",
+		"chatId":  chatId,
+		"userIds": added,
+	})
+	for _, uid := range added {
+		go c.WS.NotifyUser(uid, "group_added", "Anda ditambahkan ke grup "+groupName(chat), chat)
+	}
+
+	utils.SuccessResponse(ctx, "Anggota berhasil ditambahkan", gin.H{"added": added})
+}
+
+// RemoveMember mengeluarkan anggota dari grup. Admin hanya bisa mengeluarkan member biasa,
+// owner bisa mengeluarkan siapa saja kecuali dirinya sendiri.
+func (c *GroupController) RemoveMember(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+	targetId := ctx.Param("userId")
+
+	if targetId == userId {
+		utils.BadRequest(ctx, "Gunakan fitur keluar grup untuk diri sendiri", nil)
+		return
+	}
+
+	actor, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengeluarkan anggota", err)
+		return
+	}
+	chat := actor.Chat()
+
+	target, err := c.ChatRepo.GetParticipant(ctx.Request.Context(), chatId, targetId)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengeluarkan anggota", err)
+		return
+	}
+	if !outranks(actor.Role, target.Role) {
+		utils.Forbidden(ctx, "Anda tidak bisa mengeluarkan anggota dengan role ini", nil)
+		return
+	}
+
+	// Akses riwayat langsung hilang begitu baris Participant dihapus
+	if err := c.ChatRepo.RemoveParticipant(ctx.Request.Context(), chatId, targetId); err != nil {
+		respondChatError(ctx, "Gagal mengeluarkan anggota", err)
+		return
+	}
+
+	info := userName + " mengeluarkan " + c.joinNames(ctx, []string{targetId})
+	c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	c.WS.NotifyGroup(chatId, userId, info, gin.H{
+		"action": "member_removed",
+		"chatId": chatId,
+		"userId": targetId,
+	})
+	go c.WS.NotifyUser(targetId, "group_removed", "Anda dikeluarkan dari grup "+groupName(chat), gin.H{"chatId": chatId})
+
+	utils.SuccessResponse(ctx, "Anggota berhasil dikeluarkan", nil)
+}
+
+// LeaveGroup membuat user keluar dari grup. Jika owner keluar, kepemilikan otomatis
+// dialihkan ke admin paling lama (atau anggota paling lama jika tidak ada admin).
+func (c *GroupController) LeaveGroup(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+
+	chat, err := c.ChatRepo.GetChatByID(ctx.Request.Context(), chatId)
+	if err != nil {
+		respondChatError(ctx, "Gagal keluar dari grup", err)
+		return
+	}
+	if !chat.IsGroup {
+		respondChatError(ctx, "Gagal keluar dari grup", repositories.ErrNotGroup)
+		return
+	}
+
+	if err := c.ChatRepo.RemoveParticipant(ctx.Request.Context(), chatId, userId); err != nil {
+		respondChatError(ctx, "Gagal keluar dari grup", err)
+		return
+	}
+
+	info := userName + " keluar dari grup"
+	c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	c.WS.NotifyGroup(chatId, userId, info, gin.H{
+		"action": "member_left",
+		"chatId": chatId,
+		"userId": userId,
+	})
+
+	newOwner, err := c.ChatRepo.EnsureGroupOwner(ctx.Request.Context(), chatId)
+	if err == nil && newOwner != nil {
+		info := newOwner.User().Name + " sekarang menjadi pemilik grup"
+		c.WS.PostInfoMessage(ctx.Request.Context(), newOwner.UserID, chatId, info)
+		c.WS.NotifyGroup(chatId, "", info, gin.H{
+			"action": "role_updated",
+			"chatId": chatId,
+			"userId": newOwner.UserID,
+			"role":   db.ParticipantRoleOwner,
+		})
+	}
+
+	utils.SuccessResponse(ctx, "Berhasil keluar dari grup", nil)
+}
+
+// UpdateGroup mengubah nama, ikon, dan/atau deskripsi grup (sesuai izin editInfo).
+func (c *GroupController) UpdateGroup(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+
+	var input models.UpdateGroupDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+	if input.Name == nil && input.Icon == nil && input.Description == nil {
+		utils.BadRequest(ctx, "Tidak ada perubahan yang dikirim", nil)
+		return
+	}
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionEditInfo); err != nil {
+		respondChatError(ctx, "Gagal mengubah info grup", err)
+		return
+	}
+
+	chat, err := c.ChatRepo.UpdateGroupInfo(ctx.Request.Context(), chatId, input.Name, input.Icon, input.Description)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengubah info grup", err)
+		return
+	}
+
+	// Satu pesan INFO per jenis perubahan, seperti aplikasi chat pada umumnya
+	var infos []string
+	if input.Name != nil {
+		infos = append(infos, userName+" mengubah nama grup menjadi \""+*input.Name+"\"")
+	}
+	if input.Icon != nil {
+		infos = append(infos, userName+" mengubah ikon grup")
+	}
+	if input.Description != nil {
+		infos = append(infos, userName+" mengubah deskripsi grup")
+	}
+	for _, info := range infos {
+		c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	}
+	c.WS.NotifyGroup(chatId, userId, strings.Join(infos, ", "), gin.H{
+		"action": "group_updated",
+		"chat":   chat,
+	})
+
+	utils.SuccessResponse(ctx, "Info grup berhasil diubah", chat)
+}
+
+// GetMembers mengambil daftar anggota grup beserta role-nya.
+func (c *GroupController) GetMembers(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), chatId, userId) {
+		respondChatError(ctx, "Gagal mengambil anggota", repositories.ErrNotParticipant)
+		return
+	}
+
+	participants, err := c.ChatRepo.GetParticipants(ctx.Request.Context(), chatId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil anggota", err)
+		return
+	}
+	blockers, err := c.ChatRepo.BlockerIDs(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil anggota", err)
+		return
+	}
+
+	res := make([]models.MemberDTO, len(participants))
+	for i, p := range participants {
+		user := p.User()
+		res[i] = models.MemberDTO{
+			UserID:   p.UserID,
+			Name:     user.Name,
+			Role:     string(p.Role),
+			JoinedAt: p.JoinedAt,
+		}
+		if v, ok := user.AvatarURL(); ok && !blockers[p.UserID] {
+			res[i].AvatarUrl = v
+		}
+	}
+	utils.SuccessResponse(ctx, "Daftar anggota ditemukan", res)
+}
+
+// UpdateMemberRole mempromosikan/menurunkan anggota, atau mengalihkan kepemilikan (role OWNER).
+// Admin bisa mempromosikan member menjadi admin; hanya owner yang bisa menurunkan admin
+// dan mengalihkan kepemilikan.
+func (c *GroupController) UpdateMemberRole(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+	targetId := ctx.Param("userId")
+
+	var input models.UpdateRoleDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+	if targetId == userId {
+		utils.BadRequest(ctx, "Tidak bisa mengubah role sendiri", nil)
+		return
+	}
+
+	actor, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengubah role", err)
+		return
+	}
+	target, err := c.ChatRepo.GetParticipant(ctx.Request.Context(), chatId, targetId)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengubah role", err)
+		return
+	}
+
+	newRole := db.ParticipantRole(input.Role)
+	if target.Role == newRole {
+		utils.SuccessResponse(ctx, "Role tidak berubah", nil)
+		return
+	}
+
+	isOwner := actor.Role == db.ParticipantRoleOwner
+	switch {
+	case target.Role == db.ParticipantRoleOwner:
+		utils.Forbidden(ctx, "Role owner tidak bisa diubah", nil)
+		return
+	case newRole == db.ParticipantRoleOwner || target.Role == db.ParticipantRoleAdmin:
+		// Alih kepemilikan dan menurunkan admin hanya boleh oleh owner
+		if !isOwner {
+			utils.Forbidden(ctx, "Hanya owner yang boleh melakukan aksi ini", nil)
+			return
+		}
+	}
+
+	if newRole == db.ParticipantRoleOwner {
+		err = c.ChatRepo.TransferOwnership(ctx.Request.Context(), chatId, userId, targetId)
+	} else {
+		_, err = c.ChatRepo.SetParticipantRole(ctx.Request.Context(), chatId, targetId, newRole)
+	}
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengubah role", err)
+		return
+	}
+
+	targetName := c.joinNames(ctx, []string{targetId})
+	var info string
+	switch newRole {
+	case db.ParticipantRoleOwner:
+		info = userName + " mengalihkan kepemilikan grup ke " + targetName
+	case db.ParticipantRoleAdmin:
+		info = userName + " menjadikan " + targetName + " admin"
+	default:
+		info = userName + " memberhentikan " + targetName + " sebagai admin"
+	}
+	c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	c.WS.NotifyGroup(chatId, userId, info, gin.H{
+		"action": "role_updated",
+		"chatId": chatId,
+		"userId": targetId,
+		"role":   newRole,
+	})
+
+	utils.SuccessResponse(ctx, "Role berhasil diubah", gin.H{"userId": targetId, "role": newRole})
+}
+
+// UpdatePermissions mengubah matriks izin grup (khusus admin).
+func (c *GroupController) UpdatePermissions(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+	chatId := ctx.Param("id")
+
+	var input models.UpdatePermissionsDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers); err != nil {
+		respondChatError(ctx, "Gagal mengubah izin grup", err)
+		return
+	}
+
+	chat, err := c.ChatRepo.UpdateGroupPermissions(ctx.Request.Context(), chatId,
+		toGroupPermission(input.EditInfo),
+		toGroupPermission(input.AddMembers),
+		toGroupPermission(input.Pin),
+		toGroupPermission(input.Send),
+		input.JoinApproval,
+	)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengubah izin grup", err)
+		return
+	}
+
+	// Mode pengumuman perlu diumumkan di chat karena mempengaruhi semua anggota
+	if input.Send != nil {
+		info := userName + " mengizinkan semua anggota mengirim pesan"
+		if *input.Send == string(db.GroupPermissionAdmins) {
+			info = userName + " mengubah grup agar hanya admin yang bisa mengirim pesan"
+		}
+		c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	}
+	c.WS.NotifyGroup(chatId, userId, "Izin grup diperbarui", gin.H{
+		"action": "permissions_updated",
+		"chat":   chat,
+	})
+
+	utils.SuccessResponse(ctx, "Izin grup berhasil diubah", chat)
+}
+
+// outranks true jika role actor boleh mengelola (mengeluarkan) role target
+func outranks(actor, target db.ParticipantRole) bool {
+	switch actor {
+	case db.ParticipantRoleOwner:
+		return target != db.ParticipantRoleOwner
+	case db.ParticipantRoleAdmin:
+		return target == db.ParticipantRoleMember
+	}
+	return false
+}
+
+// toGroupPermission mengubah input string opsional menjadi enum izin grup
+func toGroupPermission(value *string) *db.GroupPermission {
+	if value == nil {
+		return nil
+	}
+	permission := db.GroupPermission(*value)
+	return &permission
+}
+
+// joinNames menggabungkan nama user untuk teks pesan INFO ("Bob, Carol")
+func (c *GroupController) joinNames(ctx *gin.Context, userIds []string) string {
+	users, _ := c.ChatRepo.GetUsersByIDs(ctx.Request.Context(), userIds)
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// groupName mengambil nama grup (kosong jika belum diberi nama)
+func groupName(chat *db.ChatModel) string {
+	name, _ := chat.Name()
+	return name
+}
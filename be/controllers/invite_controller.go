@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// invitePreviewMemberCount jumlah nama anggota yang ditampilkan di preview undangan
+const invitePreviewMemberCount = 5
+
+// InviteController mengatur tautan undangan grup dan persetujuan bergabung
+type InviteController struct {
+	ChatRepo   *repositories.ChatRepository
+	InviteRepo *repositories.InviteRepository
+	WS         *WSController
+}
+
+// NewInviteController inisialisasi controller undangan dengan integrasi WebSocket
+func NewInviteController(chatRepo *repositories.ChatRepository, inviteRepo *repositories.InviteRepository, ws *WSController) *InviteController {
+	return &InviteController{
+		ChatRepo:   chatRepo,
+		InviteRepo: inviteRepo,
+		WS:         ws,
+	}
+}
+
+// CreateInvite membuat tautan undangan baru (khusus admin) dengan expiry & batas pemakaian opsional.
+func (c *InviteController) CreateInvite(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.CreateInviteDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers); err != nil {
+		respondChatError(ctx, "Gagal membuat undangan", err)
+		return
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInHours != nil {
+		exp := time.Now().Add(time.Duration(*input.ExpiresInHours) * time.Hour)
+		expiresAt = &exp
+	}
+
+	invite, err := c.InviteRepo.CreateInvite(ctx.Request.Context(), chatId, userId, expiresAt, input.MaxUses)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal membuat undangan", err)
+		return
+	}
+	utils.CreatedResponse(ctx, "Undangan berhasil dibuat", invite)
+}
+
+// GetInvites mengambil daftar undangan aktif grup (khusus admin).
+func (c *InviteController) GetInvites(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers); err != nil {
+		respondChatError(ctx, "Gagal mengambil undangan", err)
+		return
+	}
+
+	invites, err := c.InviteRepo.GetActiveInvites(ctx.Request.Context(), chatId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil undangan", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Daftar undangan ditemukan", invites)
+}
+
+// RevokeInvite mencabut tautan undangan (khusus admin).
+func (c *InviteController) RevokeInvite(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+	inviteId := ctx.Param("inviteId")
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers); err != nil {
+		respondChatError(ctx, "Gagal mencabut undangan", err)
+		return
+	}
+
+	invite, err := c.InviteRepo.RevokeInvite(ctx.Request.Context(), chatId, inviteId)
+	if err != nil {
+		respondChatError(ctx, "Gagal mencabut undangan", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Undangan dicabut", invite)
+}
+
+// PreviewInvite menampilkan ringkasan grup dari token undangan sebelum bergabung.
+func (c *InviteController) PreviewInvite(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	invite, err := c.InviteRepo.GetUsableInvite(ctx.Request.Context(), ctx.Param("token"))
+	if err != nil {
+		respondInviteError(ctx, err)
+		return
+	}
+
+	chat := invite.Chat()
+	participants := chat.Participants()
+	preview := models.InvitePreviewDTO{
+		ChatID:           chat.ID,
+		Name:             groupName(chat),
+		MemberCount:      len(participants),
+		MemberNames:      []string{},
+		RequiresApproval: chat.JoinApproval,
+	}
+	if v, ok := chat.Icon(); ok {
+		preview.Icon = v
+	}
+	if v, ok := chat.Description(); ok {
+		preview.Description = v
+	}
+	for _, p := range participants {
+		if p.UserID == userId {
+			preview.AlreadyMember = true
+		}
+		if len(preview.MemberNames) < invitePreviewMemberCount {
+			preview.MemberNames = append(preview.MemberNames, p.User().Name)
+		}
+	}
+
+	utils.SuccessResponse(ctx, "Preview undangan", preview)
+}
+
+// JoinViaInvite bergabung ke grup lewat token. Jika grup memakai mode persetujuan,
+// yang dibuat adalah permintaan bergabung untuk disetujui admin.
+func (c *InviteController) JoinViaInvite(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	userName := ctx.GetString("userName")
+
+	invite, err := c.InviteRepo.GetUsableInvite(ctx.Request.Context(), ctx.Param("token"))
+	if err != nil {
+		respondInviteError(ctx, err)
+		return
+	}
+	chat := invite.Chat()
+
+	if c.ChatRepo.IsParticipant(ctx.Request.Context(), chat.ID, userId) {
+		utils.SuccessResponse(ctx, "Anda sudah menjadi anggota grup", gin.H{"status": "JOINED", "chat": chat})
+		return
+	}
+
+	// Mode persetujuan: buat permintaan & kabari admin
+	if chat.JoinApproval {
+		request, err := c.InviteRepo.CreateJoinRequest(ctx.Request.Context(), chat.ID, userId, invite.ID)
+		if err != nil {
+			utils.InternalError(ctx, "Gagal mengirim permintaan bergabung", err)
+			return
+		}
+		for _, p := range chat.Participants() {
+			if repositories.IsGroupAdmin(p.Role) {
+				go c.WS.NotifyUser(p.UserID, "join_request", userName+" ingin bergabung ke grup "+groupName(chat), request)
+			}
+		}
+		utils.SuccessResponse(ctx, "Permintaan bergabung menunggu persetujuan admin", gin.H{"status": "PENDING", "request": request})
+		return
+	}
+
+	if err := c.addMember(ctx, chat.ID, userId, userName, invite.ID); err != nil {
+		respondInviteError(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Berhasil bergabung ke grup", gin.H{"status": "JOINED", "chat": chat})
+}
+
+// GetJoinRequests mengambil permintaan bergabung yang menunggu persetujuan (khusus admin).
+func (c *InviteController) GetJoinRequests(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers); err != nil {
+		respondChatError(ctx, "Gagal mengambil permintaan bergabung", err)
+		return
+	}
+
+	requests, err := c.InviteRepo.GetPendingJoinRequests(ctx.Request.Context(), chatId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil permintaan bergabung", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Daftar permintaan bergabung", requests)
+}
+
+// ApproveJoinRequest menyetujui permintaan bergabung (khusus admin).
+func (c *InviteController) ApproveJoinRequest(ctx *gin.Context) {
+	c.decideJoinRequest(ctx, db.JoinRequestStatusApproved)
+}
+
+// RejectJoinRequest menolak permintaan bergabung (khusus admin).
+func (c *InviteController) RejectJoinRequest(ctx *gin.Context) {
+	c.decideJoinRequest(ctx, db.JoinRequestStatusRejected)
+}
+
+// decideJoinRequest adalah logika bersama untuk setuju/tolak permintaan bergabung
+func (c *InviteController) decideJoinRequest(ctx *gin.Context, status db.JoinRequestStatus) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+	requestId := ctx.Param("requestId")
+
+	actor, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionManageMembers)
+	if err != nil {
+		respondChatError(ctx, "Gagal memproses permintaan bergabung", err)
+		return
+	}
+
+	request, err := c.InviteRepo.GetPendingJoinRequest(ctx.Request.Context(), chatId, requestId)
+	if err != nil {
+		respondChatError(ctx, "Gagal memproses permintaan bergabung", err)
+		return
+	}
+
+	if status == db.JoinRequestStatusApproved {
+		// Undangan dicek ulang saat disetujui: bisa saja sudah dicabut, kedaluwarsa, atau habis kuotanya
+		inviteId, _ := request.InviteID()
+		if err := c.addMember(ctx, chatId, request.UserID, request.User().Name, inviteId); err != nil {
+			respondInviteError(ctx, err)
+			return
+		}
+	}
+
+	decided, err := c.InviteRepo.DecideJoinRequest(ctx.Request.Context(), requestId, status)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal memproses permintaan bergabung", err)
+		return
+	}
+
+	msg := "Permintaan bergabung ke grup " + groupName(actor.Chat()) + " disetujui"
+	if status == db.JoinRequestStatusRejected {
+		msg = "Permintaan bergabung ke grup " + groupName(actor.Chat()) + " ditolak"
+	}
+	go c.WS.NotifyUser(request.UserID, "join_request_decided", msg, decided)
+
+	utils.SuccessResponse(ctx, "Permintaan bergabung diproses", decided)
+}
+
+// addMember mengklaim satu pemakaian undangan, memasukkan user ke grup, lalu
+// memposting pesan INFO dan memberi tahu anggota grup.
+func (c *InviteController) addMember(ctx *gin.Context, chatId, userId, userName, inviteId string) error {
+	if inviteId != "" {
+		if err := c.InviteRepo.ClaimUse(ctx.Request.Context(), inviteId); err != nil {
+			return err
+		}
+	}
+	if _, err := c.ChatRepo.AddParticipants(ctx.Request.Context(), chatId, []string{userId}); err != nil {
+		if inviteId != "" {
+			if releaseErr := c.InviteRepo.ReleaseUse(ctx.Request.Context(), inviteId); releaseErr != nil {
+				log.Println("[Invite] Gagal mengembalikan kuota undangan:", releaseErr)
+			}
+		}
+		return err
+	}
+
+	info := userName + " bergabung lewat tautan undangan"
+	c.WS.PostInfoMessage(ctx.Request.Context(), userId, chatId, info)
+	c.WS.NotifyGroup(chatId, userId, info, gin.H{
+		"action":  "members_added",
+		"chatId":  chatId,
+		"userIds": []string{userId},
+	})
+	return nil
+}
+
+// respondInviteError memetakan error undangan ke status HTTP yang sesuai
+func respondInviteError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrInviteInvalid):
+		utils.NotFound(ctx, "Tautan undangan tidak valid", err)
+	case errors.Is(err, repositories.ErrInviteExpired),
+		errors.Is(err, repositories.ErrInviteUsedUp):
+		utils.ErrorResponse(ctx, http.StatusGone, "Tautan undangan tidak bisa dipakai lagi", err)
+	default:
+		respondChatError(ctx, "Gagal memproses undangan", err)
+	}
+}
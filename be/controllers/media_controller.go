@@ -2,15 +2,18 @@ package controllers
 
 import (
 	"chat-app-be/config"
+	"chat-app-be/repositories"
 	"chat-app-be/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type MediaController struct{}
+type MediaController struct {
+	MediaRepo *repositories.MediaRepository
+}
 
-func NewMediaController() *MediaController {
-	return &MediaController{}
+func NewMediaController(mediaRepo *repositories.MediaRepository) *MediaController {
+	return &MediaController{MediaRepo: mediaRepo}
 }
 
 // Upload menangani upload file tunggal ke Cloudinary
@@ -30,11 +33,21 @@ func (c *MediaController) Upload(ctx *gin.Context) {
 	defer src.Close()
 
 	// Upload ke Cloudinary (config.UploadFile sudah support auto-detect type)
-	url, err := config.UploadFile(ctx.Request.Context(), src)
+	uploaded, err := config.UploadFile(ctx.Request.Context(), src)
 	if err != nil {
 		utils.InternalError(ctx, "Gagal upload ke cloud", err)
 		return
 	}
+	if uploaded == nil {
+		utils.SuccessResponse(ctx, "Upload berhasil", gin.H{"url": ""})
+		return
+	}
+
+	// Catat pemilik file agar hanya file ini yang boleh dihapus saat pesannya kedaluwarsa
+	if _, err := c.MediaRepo.Record(ctx.Request.Context(), ctx.GetString("userID"), uploaded.URL, uploaded.PublicID, uploaded.ResourceType); err != nil {
+		utils.InternalError(ctx, "Gagal mencatat file", err)
+		return
+	}
 
-	utils.SuccessResponse(ctx, "Upload berhasil", gin.H{"url": url})
+	utils.SuccessResponse(ctx, "Upload berhasil", gin.H{"url": uploaded.URL})
 }
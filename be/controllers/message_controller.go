@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessageController mengatur aksi terhadap satu pesan (reaksi, forward, favorit, dll.)
+type MessageController struct {
+	ChatRepo    *repositories.ChatRepository
+	ContactRepo *repositories.ContactRepository
+	WS          *WSController
+}
+
+// NewMessageController inisialisasi controller pesan dengan integrasi WebSocket
+func NewMessageController(repo *repositories.ChatRepository, contactRepo *repositories.ContactRepository, ws *WSController) *MessageController {
+	return &MessageController{
+		ChatRepo:    repo,
+		ContactRepo: contactRepo,
+		WS:          ws,
+	}
+}
+
+// AddReaction menambahkan reaksi emoji ke pesan dan menyebarkannya secara real-time.
+func (c *MessageController) AddReaction(ctx *gin.Context) {
+	c.react(ctx, true)
+}
+
+// RemoveReaction menghapus reaksi emoji milik user dari pesan.
+func (c *MessageController) RemoveReaction(ctx *gin.Context) {
+	c.react(ctx, false)
+}
+
+// ForwardMessage meneruskan pesan ke satu atau lebih chat (tanpa upload ulang media).
+func (c *MessageController) ForwardMessage(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	var input models.ForwardMessageDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	copies, err := c.WS.ForwardMessage(ctx.Request.Context(), userId, messageId, input.ChatIDs)
+	if err != nil {
+		respondChatError(ctx, "Gagal meneruskan pesan", err)
+		return
+	}
+
+	res := make([]models.MessageDTO, len(copies))
+	for i, m := range copies {
+		res[i] = toMessageDTO(*m)
+	}
+	utils.CreatedResponse(ctx, "Pesan berhasil diteruskan", res)
+}
+
+// GetReceipts menampilkan siapa saja yang sudah membaca pesan beserta waktunya.
+func (c *MessageController) GetReceipts(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	msg, err := c.ChatRepo.GetMessageByID(ctx.Request.Context(), messageId)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengambil info baca", err)
+		return
+	}
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), msg.ChatID, userId) {
+		respondChatError(ctx, "Gagal mengambil info baca", repositories.ErrNotParticipant)
+		return
+	}
+
+	readers, err := c.ChatRepo.GetMessageReceipts(ctx.Request.Context(), msg)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil info baca", err)
+		return
+	}
+	blockers, err := c.ChatRepo.BlockerIDs(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil info baca", err)
+		return
+	}
+
+	res := make([]models.ReadReceiptDTO, len(readers))
+	for i, p := range readers {
+		user := p.User()
+		res[i] = models.ReadReceiptDTO{
+			UserID: p.UserID,
+			Name:   user.Name,
+		}
+		if v, ok := user.AvatarURL(); ok && !blockers[p.UserID] {
+			res[i].AvatarUrl = v
+		}
+		if v, ok := p.LastReadAt(); ok {
+			res[i].ReadAt = v
+		}
+	}
+	utils.SuccessResponse(ctx, "Info baca pesan", res)
+}
+
+// GetThread mengambil pesan root beserta balasan thread-nya (terlama dulu).
+// Query opsional: ?page=&limit=
+func (c *MessageController) GetThread(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	page, limit, skip := utils.ParsePagination(ctx)
+
+	root, ok := c.threadRoot(ctx, userId, "Gagal mengambil thread")
+	if !ok {
+		return
+	}
+
+	// Ambil 1 data lebih untuk tahu masih ada halaman berikutnya atau tidak
+	replies, err := c.ChatRepo.GetThreadReplies(ctx.Request.Context(), root.ID, userId, skip, limit+1)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil thread", err)
+		return
+	}
+	hasMore := len(replies) > limit
+	if hasMore {
+		replies = replies[:limit]
+	}
+
+	followers, err := c.ChatRepo.GetThreadFollowers(ctx.Request.Context(), root.ID)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil thread", err)
+		return
+	}
+
+	rootDTO := toMessageDTO(*root)
+	rootDTO.Reactions = aggregateReactions(root.Reactions(), userId)
+	rootDTO.Thread = summarizeThread(*root, followers, userId)
+
+	res := make([]models.MessageDTO, len(replies))
+	for i, m := range replies {
+		res[i] = toMessageDTO(m)
+		res[i].Reactions = aggregateReactions(m.Reactions(), userId)
+	}
+
+	utils.SuccessResponse(ctx, "Thread ditemukan", gin.H{
+		"root":       rootDTO,
+		"items":      res,
+		"pagination": models.PaginationDTO{Page: page, Limit: limit, HasMore: hasMore},
+	})
+}
+
+// MarkThreadRead menandai semua balasan thread sudah dibaca oleh user
+func (c *MessageController) MarkThreadRead(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	root, ok := c.threadRoot(ctx, userId, "Gagal menandai thread dibaca")
+	if !ok {
+		return
+	}
+	if err := c.ChatRepo.MarkThreadRead(ctx.Request.Context(), root.ID, userId); err != nil {
+		utils.InternalError(ctx, "Gagal menandai thread dibaca", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Thread ditandai sudah dibaca", nil)
+}
+
+// FollowThread membuat user menerima notifikasi balasan thread
+func (c *MessageController) FollowThread(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	root, ok := c.threadRoot(ctx, userId, "Gagal mengikuti thread")
+	if !ok {
+		return
+	}
+	if _, err := c.ChatRepo.FollowThread(ctx.Request.Context(), root.ID, userId); err != nil {
+		utils.InternalError(ctx, "Gagal mengikuti thread", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Thread diikuti", nil)
+}
+
+// UnfollowThread menghentikan notifikasi balasan thread untuk user
+func (c *MessageController) UnfollowThread(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	root, ok := c.threadRoot(ctx, userId, "Gagal berhenti mengikuti thread")
+	if !ok {
+		return
+	}
+	if err := c.ChatRepo.UnfollowThread(ctx.Request.Context(), root.ID, userId); err != nil {
+		utils.InternalError(ctx, "Gagal berhenti mengikuti thread", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Berhenti mengikuti thread", nil)
+}
+
+// threadRoot mengambil pesan root thread dari param :id dan memastikan user peserta chat-nya.
+// ID balasan thread diarahkan ke root-nya. Response error sudah dikirim jika ok == false.
+func (c *MessageController) threadRoot(ctx *gin.Context, userId, errMessage string) (*db.MessageModel, bool) {
+	msg, err := c.ChatRepo.GetMessageByID(ctx.Request.Context(), ctx.Param("id"))
+	if err == nil {
+		if rootId, isReply := msg.ThreadRootID(); isReply {
+			msg, err = c.ChatRepo.GetMessageByID(ctx.Request.Context(), rootId)
+		}
+	}
+	if err != nil {
+		respondChatError(ctx, errMessage, err)
+		return nil, false
+	}
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), msg.ChatID, userId) {
+		respondChatError(ctx, errMessage, repositories.ErrNotParticipant)
+		return nil, false
+	}
+	if msg.Type == db.MessageTypeInfo {
+		respondChatError(ctx, errMessage, repositories.ErrInvalidThread)
+		return nil, false
+	}
+	return msg, true
+}
+
+// VotePoll memberi/mengganti suara user pada polling. optionIds kosong = menarik suara.
+func (c *MessageController) VotePoll(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	var input models.VotePollDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	poll, err := c.WS.VotePoll(ctx.Request.Context(), userId, messageId, input.OptionIDs)
+	if err != nil {
+		respondChatError(ctx, "Gagal memberi suara", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Suara tersimpan", poll)
+}
+
+// ClosePoll menutup polling (khusus pembuat polling atau admin grup)
+func (c *MessageController) ClosePoll(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	poll, err := c.WS.ClosePoll(ctx.Request.Context(), userId, messageId)
+	if err != nil {
+		respondChatError(ctx, "Gagal menutup polling", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Polling ditutup", poll)
+}
+
+// GetPollVoters mengambil daftar pemilih per opsi. Tidak tersedia untuk polling anonim.
+func (c *MessageController) GetPollVoters(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	msg, err := c.ChatRepo.GetMessageByID(ctx.Request.Context(), messageId)
+	if err != nil {
+		respondChatError(ctx, "Gagal mengambil pemilih polling", err)
+		return
+	}
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), msg.ChatID, userId) {
+		respondChatError(ctx, "Gagal mengambil pemilih polling", repositories.ErrNotParticipant)
+		return
+	}
+	poll, ok := msg.Poll()
+	if !ok {
+		respondChatError(ctx, "Gagal mengambil pemilih polling", repositories.ErrNotPoll)
+		return
+	}
+	if poll.IsAnonymous {
+		respondChatError(ctx, "Gagal mengambil pemilih polling", repositories.ErrPollAnonymous)
+		return
+	}
+
+	votes, err := c.ChatRepo.GetPollVoters(ctx.Request.Context(), poll.ID)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil pemilih polling", err)
+		return
+	}
+	blockers, err := c.ChatRepo.BlockerIDs(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil pemilih polling", err)
+		return
+	}
+
+	// Kelompokkan per opsi sesuai urutan opsi polling
+	res := make([]models.PollVotersDTO, 0, len(poll.Options()))
+	index := make(map[string]int)
+	for _, o := range poll.Options() {
+		index[o.ID] = len(res)
+		res = append(res, models.PollVotersDTO{OptionID: o.ID, Voters: []models.PollVoterDTO{}})
+	}
+	for _, v := range votes {
+		i, ok := index[v.OptionID]
+		if !ok {
+			continue
+		}
+		voter := models.PollVoterDTO{
+			UserID:  v.UserID,
+			Name:    v.User().Name,
+			VotedAt: v.CreatedAt,
+		}
+		if avatar, ok := v.User().AvatarURL(); ok && !blockers[v.UserID] {
+			voter.AvatarUrl = avatar
+		}
+		res[i].Voters = append(res[i].Voters, voter)
+	}
+	utils.SuccessResponse(ctx, "Pemilih polling", res)
+}
+
+// StarMessage menandai pesan sebagai favorit (bookmark) milik user.
+func (c *MessageController) StarMessage(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	msg, err := c.ChatRepo.GetMessageByID(ctx.Request.Context(), messageId)
+	if err != nil {
+		respondChatError(ctx, "Gagal menandai pesan", err)
+		return
+	}
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), msg.ChatID, userId) {
+		respondChatError(ctx, "Gagal menandai pesan", repositories.ErrNotParticipant)
+		return
+	}
+	if msg.IsDeleted {
+		respondChatError(ctx, "Gagal menandai pesan", repositories.ErrMessageDeleted)
+		return
+	}
+
+	if _, err := c.ChatRepo.StarMessage(ctx.Request.Context(), userId, messageId); err != nil {
+		utils.InternalError(ctx, "Gagal menandai pesan", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Pesan ditandai sebagai favorit", gin.H{"messageId": messageId, "starred": true})
+}
+
+// UnstarMessage menghapus tanda favorit dari pesan.
+func (c *MessageController) UnstarMessage(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	if err := c.ChatRepo.UnstarMessage(ctx.Request.Context(), userId, messageId); err != nil {
+		utils.InternalError(ctx, "Gagal menghapus favorit", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Tanda favorit dihapus", gin.H{"messageId": messageId, "starred": false})
+}
+
+// GetStarredMessages mengambil pesan favorit user dari semua chat.
+// Query opsional: ?chatId=&type=&page=&limit=
+func (c *MessageController) GetStarredMessages(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Query("chatId")
+	msgType := ctx.Query("type")
+	page, limit, skip := utils.ParsePagination(ctx)
+
+	switch msgType {
+	case "", "TEXT", "IMAGE", "VIDEO", "DOCUMENT", "POLL", "LOCATION", "CONTACT", "AUDIO":
+	default:
+		utils.BadRequest(ctx, "Tipe pesan tidak valid", nil)
+		return
+	}
+
+	// Ambil 1 data lebih untuk tahu masih ada halaman berikutnya atau tidak
+	starred, err := c.ChatRepo.GetStarredMessages(ctx.Request.Context(), userId, chatId, msgType, skip, limit+1)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil pesan favorit", err)
+		return
+	}
+	hasMore := len(starred) > limit
+	if hasMore {
+		starred = starred[:limit]
+	}
+
+	aliases := contactAliasMap(c.ContactRepo, userId)
+	res := make([]models.StarredMessageDTO, len(starred))
+	for i, s := range starred {
+		msg := s.Message()
+		chat := msg.Chat()
+		res[i] = models.StarredMessageDTO{
+			StarredAt:  s.CreatedAt,
+			ChatID:     chat.ID,
+			ChatName:   resolveChatName(*chat, userId, aliases),
+			IsGroup:    chat.IsGroup,
+			SenderName: resolveUserName(msg.Sender(), aliases),
+			Message:    toMessageDTO(*msg),
+		}
+	}
+
+	utils.SuccessResponse(ctx, "Pesan favorit ditemukan", gin.H{
+		"items":      res,
+		"pagination": models.PaginationDTO{Page: page, Limit: limit, HasMore: hasMore},
+	})
+}
+
+// react adalah logika bersama untuk tambah/hapus reaksi
+func (c *MessageController) react(ctx *gin.Context, add bool) {
+	userId := ctx.GetString("userID")
+	messageId := ctx.Param("id")
+
+	var input models.ReactionInputDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	reactions, err := c.WS.ReactToMessage(ctx.Request.Context(), userId, messageId, input.Emoji, add)
+	if err != nil {
+		respondChatError(ctx, "Gagal memproses reaksi", err)
+		return
+	}
+
+	msg := "Reaksi ditambahkan"
+	if !add {
+		msg = "Reaksi dihapus"
+	}
+	utils.SuccessResponse(ctx, msg, gin.H{"messageId": messageId, "reactions": reactions})
+}
@@ -0,0 +1,572 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportContextLimit jumlah pesan terakhir yang ikut disalin sebagai konteks laporan
+const reportContextLimit = 10
+
+// ModerationController mengatur laporan penyalahgunaan dari user dan antrean moderasi
+type ModerationController struct {
+	ReportRepo *repositories.ReportRepository
+	ChatRepo   *repositories.ChatRepository
+	StatusRepo *repositories.StatusRepository
+	UserRepo   *repositories.UserRepository
+	WS         *WSController
+}
+
+// NewModerationController inisialisasi controller moderasi dengan integrasi WebSocket
+func NewModerationController(reportRepo *repositories.ReportRepository, chatRepo *repositories.ChatRepository, statusRepo *repositories.StatusRepository, userRepo *repositories.UserRepository, ws *WSController) *ModerationController {
+	return &ModerationController{
+		ReportRepo: reportRepo,
+		ChatRepo:   chatRepo,
+		StatusRepo: statusRepo,
+		UserRepo:   userRepo,
+		WS:         ws,
+	}
+}
+
+// reportTarget hasil penelusuran target laporan: snapshot konten & pemiliknya
+type reportTarget struct {
+	snapshot       models.ReportSnapshotDTO
+	reportedUserId string
+	chatId         string
+}
+
+// CreateReport melaporkan pesan, status, user, atau grup. Konten & konteksnya disalin saat itu
+// juga supaya laporan tetap bisa ditinjau walau kontennya kemudian dihapus.
+func (c *ModerationController) CreateReport(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	var input models.CreateReportDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	targetType := db.ReportTargetType(input.TargetType)
+	target, err := c.captureTarget(ctx.Request.Context(), userId, targetType, input.TargetID)
+	if err != nil {
+		respondModerationError(ctx, "Gagal membuat laporan", err)
+		return
+	}
+	snapshot, err := json.Marshal(target.snapshot)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal membuat laporan", err)
+		return
+	}
+
+	var extras []db.ReportSetParam
+	if input.Details != "" {
+		extras = append(extras, db.Report.Details.Set(input.Details))
+	}
+	if target.reportedUserId != "" {
+		extras = append(extras, db.Report.ReportedUser.Link(db.User.ID.Equals(target.reportedUserId)))
+	}
+	if target.chatId != "" {
+		extras = append(extras, db.Report.ChatID.Set(target.chatId))
+	}
+
+	report, err := c.ReportRepo.Create(ctx.Request.Context(), userId, targetType, input.TargetID, db.ReportReason(input.Reason), snapshot, extras...)
+	if err != nil {
+		respondModerationError(ctx, "Gagal membuat laporan", err)
+		return
+	}
+
+	utils.CreatedResponse(ctx, "Laporan terkirim dan akan ditinjau moderator", models.ReportDTO{
+		ID:         report.ID,
+		TargetType: string(report.TargetType),
+		TargetID:   report.TargetID,
+		Reason:     string(report.Reason),
+		Status:     string(report.Status),
+		CreatedAt:  report.CreatedAt,
+	})
+}
+
+// captureTarget memastikan target laporan ada & bisa diakses pelapor, lalu menyalin kontennya
+func (c *ModerationController) captureTarget(ctx context.Context, reporterId string, targetType db.ReportTargetType, targetId string) (*reportTarget, error) {
+	target := &reportTarget{
+		snapshot: models.ReportSnapshotDTO{
+			CapturedAt: time.Now(),
+			Context:    []models.ReportedMessageDTO{},
+		},
+	}
+	var contextBefore time.Time
+
+	switch targetType {
+	case db.ReportTargetTypeMessage:
+		msg, err := c.ChatRepo.GetMessageByID(ctx, targetId)
+		if err != nil {
+			return nil, notFoundAs(err, repositories.ErrReportTargetNotFound)
+		}
+		// Pesan yang tidak terlihat oleh pelapor dianggap tidak ada
+		if !c.ChatRepo.IsParticipant(ctx, msg.ChatID, reporterId) || (msg.DroppedByBlock && msg.SenderID != reporterId) {
+			return nil, repositories.ErrReportTargetNotFound
+		}
+		if msg.SenderID == reporterId {
+			return nil, repositories.ErrReportOwnContent
+		}
+		if msg.IsDeleted {
+			return nil, repositories.ErrMessageDeleted
+		}
+		reported := toReportedMessage(*msg)
+		target.snapshot.Message = &reported
+		target.reportedUserId = msg.SenderID
+		target.chatId = msg.ChatID
+		contextBefore = msg.Timestamp
+
+	case db.ReportTargetTypeStatus:
+		status, err := c.StatusRepo.GetByID(ctx, targetId)
+		if err != nil {
+			return nil, notFoundAs(err, repositories.ErrReportTargetNotFound)
+		}
+		if status.UserID == reporterId {
+			return nil, repositories.ErrReportOwnContent
+		}
+		if c.StatusRepo.IsHiddenFrom(ctx, status.ID, reporterId) {
+			return nil, repositories.ErrReportTargetNotFound
+		}
+		reported := models.ReportedStatusDTO{
+			ID:        status.ID,
+			UserID:    status.UserID,
+			Type:      string(status.Type),
+			MediaUrl:  getOptionalString(status.MediaURL),
+			Content:   getOptionalString(status.Content),
+			Caption:   getOptionalString(status.Caption),
+			CreatedAt: status.CreatedAt,
+		}
+		target.snapshot.Status = &reported
+		target.snapshot.User = toReportedUser(status.User())
+		target.reportedUserId = status.UserID
+
+	case db.ReportTargetTypeUser:
+		if targetId == reporterId {
+			return nil, repositories.ErrReportOwnContent
+		}
+		user, err := c.UserRepo.FindByID(ctx, targetId)
+		if err != nil {
+			return nil, notFoundAs(err, repositories.ErrReportTargetNotFound)
+		}
+		target.snapshot.User = toReportedUser(user)
+		target.reportedUserId = user.ID
+		// Konteks diambil dari chat direct dengan pelapor (jika pernah ada)
+		if chat, err := c.ChatRepo.FindDirectChat(ctx, reporterId, user.ID); err == nil {
+			target.chatId = chat.ID
+			contextBefore = time.Now()
+		}
+
+	case db.ReportTargetTypeGroup:
+		chat, err := c.ChatRepo.GetChatByID(ctx, targetId)
+		if err != nil {
+			return nil, notFoundAs(err, repositories.ErrReportTargetNotFound)
+		}
+		if !chat.IsGroup || !c.ChatRepo.IsParticipant(ctx, chat.ID, reporterId) {
+			return nil, repositories.ErrReportTargetNotFound
+		}
+		members, err := c.ChatRepo.GetParticipants(ctx, chat.ID)
+		if err != nil {
+			return nil, err
+		}
+		description, _ := chat.Description()
+		icon, _ := chat.Icon()
+		target.snapshot.Group = &models.ReportedGroupDTO{
+			ID:          chat.ID,
+			Name:        groupName(chat),
+			Description: description,
+			Icon:        icon,
+			MemberCount: len(members),
+		}
+		target.chatId = chat.ID
+		contextBefore = time.Now()
+
+	default:
+		return nil, repositories.ErrReportTargetNotFound
+	}
+
+	if target.chatId != "" && !contextBefore.IsZero() {
+		recent, err := c.ChatRepo.GetMessagesBefore(ctx, target.chatId, reporterId, contextBefore, reportContextLimit)
+		if err != nil {
+			return nil, err
+		}
+		// Disimpan terlama dulu supaya mudah dibaca seperti percakapan
+		for i := len(recent) - 1; i >= 0; i-- {
+			target.snapshot.Context = append(target.snapshot.Context, toReportedMessage(recent[i]))
+		}
+	}
+	return target, nil
+}
+
+// GetQueue mengambil antrean laporan untuk moderator.
+// Filter: ?status= (default laporan yang masih terbuka) dan ?type= jenis target.
+func (c *ModerationController) GetQueue(ctx *gin.Context) {
+	page, limit, skip := utils.ParsePagination(ctx)
+
+	var statuses []db.ReportStatus
+	switch status := ctx.Query("status"); status {
+	case "":
+		statuses = []db.ReportStatus{db.ReportStatusOpen, db.ReportStatusInReview}
+	case "OPEN", "IN_REVIEW", "RESOLVED", "DISMISSED":
+		statuses = []db.ReportStatus{db.ReportStatus(status)}
+	default:
+		utils.BadRequest(ctx, "Status laporan tidak valid", nil)
+		return
+	}
+	targetType := ctx.Query("type")
+	switch targetType {
+	case "", "MESSAGE", "STATUS", "USER", "GROUP":
+	default:
+		utils.BadRequest(ctx, "Jenis laporan tidak valid", nil)
+		return
+	}
+
+	// Ambil 1 data lebih untuk tahu masih ada halaman berikutnya atau tidak
+	reports, err := c.ReportRepo.GetQueue(ctx.Request.Context(), statuses, targetType, skip, limit+1)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil antrean laporan", err)
+		return
+	}
+	hasMore := len(reports) > limit
+	if hasMore {
+		reports = reports[:limit]
+	}
+
+	res := make([]models.ModerationReportDTO, len(reports))
+	for i, r := range reports {
+		res[i] = toModerationReportDTO(r)
+	}
+	utils.SuccessResponse(ctx, "Antrean laporan", gin.H{
+		"items":      res,
+		"pagination": models.PaginationDTO{Page: page, Limit: limit, HasMore: hasMore},
+	})
+}
+
+// GetReport mengambil detail laporan lengkap dengan snapshot dan riwayat tindakan.
+func (c *ModerationController) GetReport(ctx *gin.Context) {
+	c.respondReport(ctx, ctx.Param("id"), "Detail laporan")
+}
+
+// ClaimReport menandai laporan sedang ditinjau oleh moderator yang login.
+func (c *ModerationController) ClaimReport(ctx *gin.Context) {
+	moderatorId := ctx.GetString("userID")
+	reportId := ctx.Param("id")
+
+	if _, err := c.ReportRepo.Claim(ctx.Request.Context(), reportId, moderatorId); err != nil {
+		respondModerationError(ctx, "Gagal mengambil laporan", err)
+		return
+	}
+	c.respondReport(ctx, reportId, "Laporan sedang Anda tinjau")
+}
+
+// TakeAction menjalankan tindakan moderator atas laporan (hapus konten, peringatan,
+// tangguhkan akun, bubarkan grup) lalu mencatatnya di riwayat laporan.
+// Laporan otomatis berpindah ke IN_REVIEW atas nama moderator tersebut.
+func (c *ModerationController) TakeAction(ctx *gin.Context) {
+	moderatorId := ctx.GetString("userID")
+	reportId := ctx.Param("id")
+
+	var input models.ModerationActionInputDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	report, err := c.ReportRepo.Claim(ctx.Request.Context(), reportId, moderatorId)
+	if err != nil {
+		respondModerationError(ctx, "Gagal menjalankan tindakan moderasi", err)
+		return
+	}
+
+	actionType := db.ModerationActionType(input.Type)
+	var suspendedUntil *time.Time
+	switch actionType {
+	case db.ModerationActionTypeDeleteContent:
+		err = c.deleteContent(ctx.Request.Context(), report)
+	case db.ModerationActionTypeWarn:
+		err = c.warnUser(report, input.Note)
+	case db.ModerationActionTypeSuspend:
+		var until time.Time
+		until, err = c.suspendUser(ctx.Request.Context(), moderatorId, report, input.DurationHours)
+		suspendedUntil = &until
+	case db.ModerationActionTypeDisbandGroup:
+		err = c.disbandGroup(ctx.Request.Context(), report)
+	}
+	if err != nil {
+		respondModerationError(ctx, "Gagal menjalankan tindakan moderasi", err)
+		return
+	}
+
+	if _, err := c.ReportRepo.AddAction(ctx.Request.Context(), reportId, moderatorId, actionType, input.Note, suspendedUntil); err != nil {
+		utils.InternalError(ctx, "Tindakan dijalankan tetapi gagal dicatat", err)
+		return
+	}
+	c.respondReport(ctx, reportId, "Tindakan moderasi dijalankan")
+}
+
+// ResolveReport menutup laporan sebagai RESOLVED atau DISMISSED beserta catatannya.
+// Pelapor diberi tahu bahwa laporannya sudah ditinjau.
+func (c *ModerationController) ResolveReport(ctx *gin.Context) {
+	moderatorId := ctx.GetString("userID")
+	reportId := ctx.Param("id")
+
+	var input models.ResolveReportDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+
+	report, err := c.ReportRepo.Resolve(ctx.Request.Context(), reportId, moderatorId, db.ReportStatus(input.Status), input.Resolution)
+	if err != nil {
+		respondModerationError(ctx, "Gagal menutup laporan", err)
+		return
+	}
+	if reporterId, ok := report.ReporterID(); ok {
+		go c.WS.NotifyUser(reporterId, "report_resolved", "Laporan Anda sudah ditinjau moderator", gin.H{
+			"reportId": report.ID,
+			"status":   report.Status,
+		})
+	}
+	c.respondReport(ctx, reportId, "Laporan ditutup")
+}
+
+// deleteContent menghapus pesan (untuk semua peserta) atau status yang dilaporkan
+func (c *ModerationController) deleteContent(ctx context.Context, report *db.ReportModel) error {
+	switch report.TargetType {
+	case db.ReportTargetTypeMessage:
+		msg, err := c.ChatRepo.DeleteMessageForEveryone(ctx, report.TargetID)
+		if err != nil {
+			return err
+		}
+		c.WS.BroadcastEvent(msg.ChatID, WSMessage{
+			Type:   "message_deleted",
+			ChatID: msg.ChatID,
+			Data:   gin.H{"messageId": msg.ID},
+		})
+		go c.WS.NotifyUser(msg.SenderID, "content_removed", "Pesan Anda dihapus moderator karena melanggar ketentuan", gin.H{
+			"chatId":    msg.ChatID,
+			"messageId": msg.ID,
+		})
+		return nil
+
+	case db.ReportTargetTypeStatus:
+		if err := c.StatusRepo.DeleteStatus(ctx, report.TargetID); err != nil {
+			return err
+		}
+		if ownerId, ok := report.ReportedUserID(); ok {
+			go c.WS.NotifyUser(ownerId, "content_removed", "Status Anda dihapus moderator karena melanggar ketentuan", gin.H{
+				"statusId": report.TargetID,
+			})
+		}
+		return nil
+	}
+	return repositories.ErrModerationActionNotApplicable
+}
+
+// warnUser mengirim peringatan ke pemilik konten yang dilaporkan
+func (c *ModerationController) warnUser(report *db.ReportModel, note string) error {
+	userId, ok := report.ReportedUserID()
+	if !ok {
+		return repositories.ErrModerationActionNotApplicable
+	}
+	go c.WS.NotifyUser(userId, "moderation_warning", "Akun Anda mendapat peringatan dari moderator", gin.H{
+		"reportId": report.ID,
+		"reason":   report.Reason,
+		"note":     note,
+	})
+	return nil
+}
+
+// suspendUser menangguhkan akun pemilik konten selama durationHours dan memutus semua socket-nya
+func (c *ModerationController) suspendUser(ctx context.Context, moderatorId string, report *db.ReportModel, durationHours int) (time.Time, error) {
+	userId, ok := report.ReportedUserID()
+	if !ok || userId == moderatorId {
+		return time.Time{}, repositories.ErrModerationActionNotApplicable
+	}
+	if durationHours <= 0 {
+		return time.Time{}, repositories.ErrSuspendDurationRequired
+	}
+
+	until := time.Now().Add(time.Duration(durationHours) * time.Hour)
+	if _, err := c.UserRepo.SuspendUser(ctx, userId, until); err != nil {
+		return time.Time{}, err
+	}
+	c.WS.DisconnectUser(userId)
+	return until, nil
+}
+
+// disbandGroup membubarkan grup tempat konten dilaporkan dan memberi tahu semua mantan anggotanya
+func (c *ModerationController) disbandGroup(ctx context.Context, report *db.ReportModel) error {
+	chatId, ok := report.ChatID()
+	if !ok {
+		return repositories.ErrModerationActionNotApplicable
+	}
+	chat, err := c.ChatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	if !chat.IsGroup {
+		return repositories.ErrModerationActionNotApplicable
+	}
+
+	memberIds, err := c.ChatRepo.DisbandGroup(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	for _, uid := range memberIds {
+		go c.WS.NotifyUser(uid, "group_disbanded", "Grup "+groupName(chat)+" dibubarkan moderator", gin.H{"chatId": chat.ID})
+	}
+	return nil
+}
+
+// respondReport mengirim detail laporan terbaru (snapshot, riwayat tindakan, jumlah laporan lain)
+func (c *ModerationController) respondReport(ctx *gin.Context, reportId, message string) {
+	report, err := c.ReportRepo.GetByID(ctx.Request.Context(), reportId)
+	if err != nil {
+		respondModerationError(ctx, "Gagal mengambil laporan", err)
+		return
+	}
+
+	dto := toModerationReportDTO(*report)
+	dto.Snapshot = json.RawMessage(report.Snapshot)
+	for _, a := range report.Actions() {
+		action := models.ModerationActionDTO{
+			ID:        a.ID,
+			Type:      string(a.Type),
+			CreatedAt: a.CreatedAt,
+		}
+		if moderator, ok := a.Moderator(); ok {
+			action.Moderator = toModerationUser(moderator)
+		}
+		if v, ok := a.Note(); ok {
+			action.Note = v
+		}
+		if v, ok := a.SuspendedUntil(); ok {
+			action.SuspendedUntil = &v
+		}
+		dto.Actions = append(dto.Actions, action)
+	}
+	if userId, ok := report.ReportedUserID(); ok {
+		if count, err := c.ReportRepo.CountOpenAgainst(ctx.Request.Context(), userId); err == nil {
+			dto.OpenReportsCount = count
+		}
+	}
+	utils.SuccessResponse(ctx, message, dto)
+}
+
+// respondModerationError memetakan error laporan & moderasi ke status HTTP yang sesuai
+func respondModerationError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrReportDuplicate),
+		errors.Is(err, repositories.ErrReportClosed):
+		utils.Conflict(ctx, message, err)
+	case errors.Is(err, repositories.ErrReportTargetNotFound):
+		utils.NotFound(ctx, message, err)
+	case errors.Is(err, db.ErrNotFound):
+		utils.NotFound(ctx, "Laporan atau konten yang dilaporkan tidak ditemukan", err)
+	case errors.Is(err, repositories.ErrReportOwnContent),
+		errors.Is(err, repositories.ErrModerationActionNotApplicable),
+		errors.Is(err, repositories.ErrSuspendDurationRequired),
+		errors.Is(err, repositories.ErrMessageDeleted):
+		utils.BadRequest(ctx, message, err)
+	default:
+		utils.InternalError(ctx, message, err)
+	}
+}
+
+// notFoundAs mengganti db.ErrNotFound dengan error yang lebih jelas untuk client
+func notFoundAs(err, replacement error) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return replacement
+	}
+	return err
+}
+
+// toReportedMessage menyalin pesan ke snapshot laporan (nama pengirim jika relasinya di-fetch)
+func toReportedMessage(m db.MessageModel) models.ReportedMessageDTO {
+	dto := models.ReportedMessageDTO{
+		ID:        m.ID,
+		SenderID:  m.SenderID,
+		Type:      string(m.Type),
+		Content:   m.Content,
+		IsDeleted: m.IsDeleted,
+		Timestamp: m.Timestamp,
+	}
+	if sender := m.RelationsMessage.Sender; sender != nil {
+		dto.SenderName = sender.Name
+	}
+	if v, ok := m.EditedAt(); ok {
+		dto.EditedAt = &v
+	}
+	if v, ok := m.Payload(); ok {
+		dto.Payload = json.RawMessage(v)
+	}
+	return dto
+}
+
+// toReportedUser menyalin profil user ke snapshot laporan
+func toReportedUser(u *db.UserModel) *models.ReportedUserDTO {
+	dto := &models.ReportedUserDTO{
+		ID:   u.ID,
+		Name: u.Name,
+	}
+	if v, ok := u.Username(); ok {
+		dto.Username = v
+	}
+	if v, ok := u.AvatarURL(); ok {
+		dto.AvatarUrl = v
+	}
+	return dto
+}
+
+// toModerationUser identitas singkat user untuk moderator
+func toModerationUser(u *db.UserModel) *models.ModerationUserDTO {
+	return &models.ModerationUserDTO{
+		ID:    u.ID,
+		Name:  u.Name,
+		Email: u.Email,
+	}
+}
+
+// toModerationReportDTO mengubah model laporan menjadi DTO antrean (tanpa snapshot & riwayat tindakan)
+func toModerationReportDTO(r db.ReportModel) models.ModerationReportDTO {
+	dto := models.ModerationReportDTO{
+		ID:         r.ID,
+		TargetType: string(r.TargetType),
+		TargetID:   r.TargetID,
+		Reason:     string(r.Reason),
+		Status:     string(r.Status),
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+	if v, ok := r.ChatID(); ok {
+		dto.ChatID = v
+	}
+	if v, ok := r.Details(); ok {
+		dto.Details = v
+	}
+	if v, ok := r.Resolution(); ok {
+		dto.Resolution = v
+	}
+	if v, ok := r.ResolvedAt(); ok {
+		dto.ResolvedAt = &v
+	}
+	if u, ok := r.Reporter(); ok {
+		dto.Reporter = toModerationUser(u)
+	}
+	if u, ok := r.ReportedUser(); ok {
+		dto.ReportedUser = toModerationUser(u)
+	}
+	if u, ok := r.Assignee(); ok {
+		dto.Assignee = toModerationUser(u)
+	}
+	return dto
+}
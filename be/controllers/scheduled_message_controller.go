@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scheduleMaxAhead batas terjauh pesan boleh dijadwalkan
+const scheduleMaxAhead = 365 * 24 * time.Hour
+
+// scheduleBatchSize jumlah maksimal pesan terjadwal yang diproses per putaran scheduler
+const scheduleBatchSize = 100
+
+// ScheduledMessageController mengatur pesan terjadwal beserta scheduler pengirimnya
+type ScheduledMessageController struct {
+	ScheduledRepo *repositories.ScheduledMessageRepository
+	ChatRepo      *repositories.ChatRepository
+	WS            *WSController
+}
+
+// NewScheduledMessageController inisialisasi controller pesan terjadwal dengan integrasi WebSocket
+func NewScheduledMessageController(scheduledRepo *repositories.ScheduledMessageRepository, chatRepo *repositories.ChatRepository, ws *WSController) *ScheduledMessageController {
+	return &ScheduledMessageController{
+		ScheduledRepo: scheduledRepo,
+		ChatRepo:      chatRepo,
+		WS:            ws,
+	}
+}
+
+// Create menjadwalkan pesan untuk dikirim nanti di chat ini.
+func (c *ScheduledMessageController) Create(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	var input models.CreateScheduledMessageDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+	if !validSendAt(input.SendAt) {
+		utils.BadRequest(ctx, "Waktu kirim harus di masa depan dan maksimal 1 tahun ke depan", nil)
+		return
+	}
+
+	// Validasi awal; saat waktunya tiba semuanya dicek ulang oleh SendMessage
+	if _, err := c.ChatRepo.CheckPermission(ctx.Request.Context(), chatId, userId, repositories.ActionSend); err != nil {
+		respondChatError(ctx, "Gagal menjadwalkan pesan", err)
+		return
+	}
+	if input.ReplyToID != "" {
+		if _, err := c.ChatRepo.FindMessageInChat(ctx.Request.Context(), chatId, input.ReplyToID); err != nil {
+			respondChatError(ctx, "Gagal menjadwalkan pesan", err)
+			return
+		}
+	}
+
+	msgType := db.MessageTypeText
+	if input.Type != "" {
+		msgType = db.MessageType(input.Type)
+	}
+
+	scheduled, err := c.ScheduledRepo.Create(ctx.Request.Context(), chatId, userId, input.Content, msgType, input.ReplyToID, input.SendAt)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal menjadwalkan pesan", err)
+		return
+	}
+	utils.CreatedResponse(ctx, "Pesan dijadwalkan", toScheduledMessageDTO(*scheduled))
+}
+
+// List mengambil pesan terjadwal milik user di chat ini yang belum dikirim.
+func (c *ScheduledMessageController) List(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), chatId, userId) {
+		respondChatError(ctx, "Gagal mengambil pesan terjadwal", repositories.ErrNotParticipant)
+		return
+	}
+
+	scheduled, err := c.ScheduledRepo.GetPending(ctx.Request.Context(), chatId, userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil pesan terjadwal", err)
+		return
+	}
+
+	res := make([]models.ScheduledMessageDTO, len(scheduled))
+	for i, s := range scheduled {
+		res[i] = toScheduledMessageDTO(s)
+	}
+	utils.SuccessResponse(ctx, "Daftar pesan terjadwal", res)
+}
+
+// Update mengubah isi atau waktu kirim pesan terjadwal yang belum dikirim.
+func (c *ScheduledMessageController) Update(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+	scheduledId := ctx.Param("scheduledId")
+
+	var input models.UpdateScheduledMessageDTO
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		utils.ValidationErrorResponse(ctx, utils.FormatValidationError(err))
+		return
+	}
+	if input.Content == nil && input.SendAt == nil {
+		utils.BadRequest(ctx, "Tidak ada perubahan yang dikirim", nil)
+		return
+	}
+	if input.SendAt != nil && !validSendAt(*input.SendAt) {
+		utils.BadRequest(ctx, "Waktu kirim harus di masa depan dan maksimal 1 tahun ke depan", nil)
+		return
+	}
+
+	if _, err := c.ScheduledRepo.GetOwnedPending(ctx.Request.Context(), scheduledId, chatId, userId); err != nil {
+		respondScheduledError(ctx, "Gagal mengubah pesan terjadwal", err)
+		return
+	}
+
+	scheduled, err := c.ScheduledRepo.Update(ctx.Request.Context(), scheduledId, input.Content, input.SendAt)
+	if err != nil {
+		respondScheduledError(ctx, "Gagal mengubah pesan terjadwal", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Pesan terjadwal diperbarui", toScheduledMessageDTO(*scheduled))
+}
+
+// Cancel membatalkan pesan terjadwal yang belum dikirim.
+func (c *ScheduledMessageController) Cancel(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+	scheduledId := ctx.Param("scheduledId")
+
+	if _, err := c.ScheduledRepo.GetOwnedPending(ctx.Request.Context(), scheduledId, chatId, userId); err != nil {
+		respondScheduledError(ctx, "Gagal membatalkan pesan terjadwal", err)
+		return
+	}
+
+	if err := c.ScheduledRepo.Cancel(ctx.Request.Context(), scheduledId); err != nil {
+		respondScheduledError(ctx, "Gagal membatalkan pesan terjadwal", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Pesan terjadwal dibatalkan", gin.H{"id": scheduledId})
+}
+
+// RunScheduler berjalan di background dan mengirim pesan terjadwal yang waktunya sudah tiba.
+// Status tersimpan di database sehingga jadwal tetap jalan setelah server restart.
+// Interval dibaca dari SCHEDULER_INTERVAL_SECONDS (default 5 detik).
+func (c *ScheduledMessageController) RunScheduler() {
+	seconds, _ := strconv.Atoi(os.Getenv("SCHEDULER_INTERVAL_SECONDS"))
+	if seconds <= 0 {
+		seconds = 5
+	}
+
+	// Pesan yang tertahan saat server mati di tengah pengiriman dikirim ulang (kecuali yang sudah tersimpan)
+	if err := c.ScheduledRepo.RecoverInterrupted(context.Background()); err != nil {
+		log.Println("[Scheduler] Gagal memulihkan pesan terjadwal:", err)
+	}
+
+	ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.dispatchDue()
+	}
+}
+
+// dispatchDue mengirim semua pesan terjadwal yang sudah jatuh tempo
+func (c *ScheduledMessageController) dispatchDue() {
+	ctx := context.Background()
+	for {
+		due, err := c.ScheduledRepo.GetDue(ctx, time.Now(), scheduleBatchSize)
+		if err != nil {
+			log.Println("[Scheduler] Gagal mengambil pesan terjadwal:", err)
+			return
+		}
+		for _, s := range due {
+			c.dispatch(ctx, s)
+		}
+		if len(due) < scheduleBatchSize {
+			return
+		}
+	}
+}
+
+// dispatch mengirim satu pesan terjadwal lewat jalur kirim normal (CreateMessage + fan-out WS).
+// Jika pengirim sudah bukan anggota / tidak boleh mengirim / chat sudah tidak ada, pesan ditandai FAILED
+// dan pengirim diberi tahu.
+func (c *ScheduledMessageController) dispatch(ctx context.Context, s db.ScheduledMessageModel) {
+	claimed, err := c.ScheduledRepo.Claim(ctx, s.ID)
+	if err != nil || !claimed {
+		return
+	}
+
+	input := models.SendMessageDTO{
+		Content: s.Content,
+		Type:    string(s.Type),
+	}
+	if v, ok := s.ReplyToID(); ok {
+		input.ReplyToID = v
+	}
+
+	// ID jadwal ikut disimpan di pesan dalam langkah yang sama, sehingga RecoverInterrupted
+	// tahu pesan ini sudah terkirim walau server mati sebelum MarkSent
+	origin := db.Message.ScheduledMessageID.Set(s.ID)
+	msg, err := c.WS.SendMessage(ctx, s.SenderID, s.ChatID, input, origin)
+	// Pesan yang dibalas sudah hilang (mis. pesan sementara) atau dihapus: tetap kirim tanpa reply
+	if (errors.Is(err, repositories.ErrMessageNotInChat) || errors.Is(err, repositories.ErrMessageDeleted)) && input.ReplyToID != "" {
+		input.ReplyToID = ""
+		msg, err = c.WS.SendMessage(ctx, s.SenderID, s.ChatID, input, origin)
+	}
+	if err != nil {
+		reason := utils.SafeErrorMessage(err)
+		if errors.Is(err, db.ErrNotFound) {
+			reason = "chat sudah tidak tersedia"
+		}
+		if markErr := c.ScheduledRepo.MarkFailed(ctx, s.ID, reason); markErr != nil {
+			log.Println("[Scheduler] Gagal menandai pesan terjadwal FAILED:", markErr)
+		}
+		c.WS.NotifyUser(s.SenderID, "scheduled_failed", "Pesan terjadwal gagal dikirim: "+reason, gin.H{
+			"id":     s.ID,
+			"chatId": s.ChatID,
+			"reason": reason,
+		})
+		return
+	}
+
+	if err := c.ScheduledRepo.MarkSent(ctx, s.ID, msg.ID); err != nil {
+		log.Println("[Scheduler] Gagal menandai pesan terjadwal SENT:", err)
+	}
+}
+
+// validSendAt memastikan waktu kirim ada di masa depan dan tidak terlalu jauh
+func validSendAt(sendAt time.Time) bool {
+	now := time.Now()
+	return sendAt.After(now) && sendAt.Before(now.Add(scheduleMaxAhead))
+}
+
+// respondScheduledError memetakan error pesan terjadwal ke status HTTP yang sesuai
+func respondScheduledError(ctx *gin.Context, message string, err error) {
+	if errors.Is(err, repositories.ErrScheduledNotPending) {
+		utils.BadRequest(ctx, message, err)
+		return
+	}
+	respondChatError(ctx, message, err)
+}
+
+// toScheduledMessageDTO mengubah model pesan terjadwal menjadi DTO
+func toScheduledMessageDTO(s db.ScheduledMessageModel) models.ScheduledMessageDTO {
+	dto := models.ScheduledMessageDTO{
+		ID:        s.ID,
+		ChatID:    s.ChatID,
+		Content:   s.Content,
+		Type:      string(s.Type),
+		SendAt:    s.SendAt,
+		Status:    string(s.Status),
+		CreatedAt: s.CreatedAt,
+	}
+	if v, ok := s.ReplyToID(); ok {
+		dto.ReplyToID = v
+	}
+	return dto
+}
@@ -1,20 +1,31 @@
 package controllers
 
 import (
+	"chat-app-be/models"
 	"chat-app-be/repositories"
 	"chat-app-be/utils"
+	"encoding/base64"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SearchController struct {
-	Repo *repositories.SearchRepository
+	Repo     *repositories.SearchRepository
+	ChatRepo *repositories.ChatRepository
 }
 
-func NewSearchController(repo *repositories.SearchRepository) *SearchController {
-	return &SearchController{Repo: repo}
+func NewSearchController(repo *repositories.SearchRepository, chatRepo *repositories.ChatRepository) *SearchController {
+	return &SearchController{Repo: repo, ChatRepo: chatRepo}
 }
 
+// searchQueryMaxLength batas panjang kata kunci pencarian dalam chat
+const searchQueryMaxLength = 200
+
 // HandleSearch menangani pencarian global
 func (c *SearchController) HandleSearch(ctx *gin.Context) {
 	query := ctx.Query("q")
@@ -31,3 +42,150 @@ func (c *SearchController) HandleSearch(ctx *gin.Context) {
 
 	utils.SuccessResponse(ctx, "Pencarian berhasil", results)
 }
+
+// SearchChat mencari pesan di dalam satu chat (full-text, diurutkan berdasarkan relevansi).
+// Query: ?q= (wajib), &senderId=, &type=, &from=, &to= (RFC3339 atau YYYY-MM-DD), &limit=, &cursor=
+// Setiap hasil bisa dibuka di timeline lewat GET /chats/:id/messages?around=<messageId>.
+func (c *SearchController) SearchChat(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	chatId := ctx.Param("id")
+
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" {
+		utils.BadRequest(ctx, "Query pencarian tidak boleh kosong", nil)
+		return
+	}
+	if utf8.RuneCountInString(q) > searchQueryMaxLength {
+		utils.BadRequest(ctx, "Query pencarian terlalu panjang", nil)
+		return
+	}
+
+	filter := repositories.ChatSearchFilter{
+		ChatID:   chatId,
+		ViewerID: userId,
+		Query:    q,
+		SenderID: ctx.Query("senderId"),
+		Type:     ctx.Query("type"),
+	}
+	switch filter.Type {
+	case "", "TEXT", "IMAGE", "VIDEO", "DOCUMENT", "POLL", "LOCATION", "CONTACT", "AUDIO":
+	default:
+		utils.BadRequest(ctx, "Tipe pesan tidak valid", nil)
+		return
+	}
+
+	var ok bool
+	if filter.From, ok = parseSearchTime(ctx.Query("from"), false); !ok {
+		utils.BadRequest(ctx, "Format tanggal 'from' tidak valid", nil)
+		return
+	}
+	if filter.To, ok = parseSearchTime(ctx.Query("to"), true); !ok {
+		utils.BadRequest(ctx, "Format tanggal 'to' tidak valid", nil)
+		return
+	}
+	_, limit, _ := utils.ParsePagination(ctx)
+	if filter.Skip, ok = decodeSearchCursor(ctx.Query("cursor")); !ok {
+		utils.BadRequest(ctx, "Cursor tidak valid", nil)
+		return
+	}
+	filter.Take = limit + 1 // 1 data lebih untuk tahu masih ada halaman berikutnya
+
+	if !c.ChatRepo.IsParticipant(ctx.Request.Context(), chatId, userId) {
+		respondChatError(ctx, "Gagal mencari pesan", repositories.ErrNotParticipant)
+		return
+	}
+
+	hits, err := c.Repo.SearchChatMessages(ctx.Request.Context(), filter)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mencari pesan", err)
+		return
+	}
+	var nextCursor string
+	if len(hits) > limit {
+		hits = hits[:limit]
+		nextCursor = encodeSearchCursor(filter.Skip + limit)
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = string(h.ID)
+	}
+	messages, err := c.ChatRepo.GetMessagesByIDs(ctx.Request.Context(), ids)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mencari pesan", err)
+		return
+	}
+	byID := make(map[string]models.MessageDTO, len(messages))
+	for _, m := range messages {
+		dto := toMessageDTO(m)
+		dto.Reactions = aggregateReactions(m.Reactions(), userId)
+		byID[m.ID] = dto
+	}
+
+	// Pertahankan urutan relevansi dari hasil pencarian
+	res := make([]models.ChatSearchResultDTO, 0, len(hits))
+	for _, h := range hits {
+		msg, ok := byID[string(h.ID)]
+		if !ok {
+			continue
+		}
+		res = append(res, models.ChatSearchResultDTO{
+			Message: msg,
+			Snippet: highlightSnippet(string(h.Snippet)),
+			Rank:    float64(h.Rank),
+		})
+	}
+
+	utils.SuccessResponse(ctx, "Pencarian berhasil", gin.H{
+		"items":      res,
+		"nextCursor": nextCursor,
+	})
+}
+
+// highlightSnippet meng-escape snippet lalu mengganti penanda kata yang cocok dengan <mark>,
+// supaya aman dirender sebagai HTML di client
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, repositories.HighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, repositories.HighlightStop, "</mark>")
+}
+
+// parseSearchTime membaca tanggal filter. Format tanggal saja (YYYY-MM-DD) untuk batas atas
+// dianggap sampai akhir hari tersebut. String kosong berarti tanpa filter.
+func parseSearchTime(value string, endOfDay bool) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, true
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, false
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Millisecond)
+	}
+	return &t, true
+}
+
+// encodeSearchCursor membungkus offset hasil pencarian menjadi cursor opaque
+func encodeSearchCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeSearchCursor membaca cursor dari encodeSearchCursor (kosong = halaman pertama)
+func decodeSearchCursor(cursor string) (int, bool) {
+	if cursor == "" {
+		return 0, true
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
@@ -45,7 +45,7 @@ func (c *StatusController) CreateStatus(ctx *gin.Context) {
 
 	// Kirim notifikasi real-time via WebSocket jika tersedia
 	if c.WS != nil {
-		go c.WS.NotifyStatusUpdate(userName, status)
+		go c.WS.NotifyStatusUpdate(userId, userName, status)
 	}
 
 	utils.SuccessResponse(ctx, "Status berhasil dibuat", status)
@@ -120,6 +120,11 @@ func (c *StatusController) ToggleLike(ctx *gin.Context) {
 	userId := ctx.GetString("userID")
 	userName := ctx.GetString("userName")
 
+	if c.StatusRepo.IsHiddenFrom(ctx.Request.Context(), statusId, userId) {
+		utils.NotFound(ctx, "Status tidak ditemukan", nil)
+		return
+	}
+
 	liked, err := c.StatusRepo.ToggleLike(ctx.Request.Context(), statusId, userId)
 	if err != nil {
 		utils.InternalError(ctx, "Gagal memproses like", err)
@@ -151,6 +156,11 @@ func (c *StatusController) ViewStatus(ctx *gin.Context) {
 	statusId := ctx.Param("id")
 	userId := ctx.GetString("userID")
 
+	if c.StatusRepo.IsHiddenFrom(ctx.Request.Context(), statusId, userId) {
+		utils.NotFound(ctx, "Status tidak ditemukan", nil)
+		return
+	}
+
 	err := c.StatusRepo.AddView(ctx.Request.Context(), statusId, userId)
 	if err != nil {
 		// Just log or ignore if duplicate
@@ -188,7 +198,8 @@ func (c *StatusController) ReplyStatus(ctx *gin.Context) {
 		db.Status.ID.Equals(statusId),
 	).Exec(ctx.Request.Context())
 
-	if status == nil {
+	// Status milik user yang memblokir kita diperlakukan seperti tidak ada
+	if status == nil || c.ChatRepo.IsBlocked(ctx.Request.Context(), status.UserID, userId) {
 		utils.BadRequest(ctx, "Status tidak ditemukan", nil)
 		return
 	}
@@ -201,7 +212,7 @@ func (c *StatusController) ReplyStatus(ctx *gin.Context) {
     // 2. Buat/Dapatkan Chat Direct antara Pengirim (userId) dan Pemilik Status (status.UserID)
     chat, err := c.ChatRepo.CreateOrGetDirectChat(ctx.Request.Context(), userId, status.UserID)
     if err != nil {
-        utils.InternalError(ctx, "Gagal membuat chat", err)
+        respondChatError(ctx, "Gagal membuat chat", err)
         return
     }
 
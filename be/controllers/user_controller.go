@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"chat-app-be/models"
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"chat-app-be/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserController mengatur endpoint seputar user lain (presence, blokir, dll.)
+type UserController struct {
+	UserRepo *repositories.UserRepository
+	WS       *WSController
+}
+
+// NewUserController inisialisasi controller user dengan integrasi WebSocket
+func NewUserController(userRepo *repositories.UserRepository, ws *WSController) *UserController {
+	return &UserController{UserRepo: userRepo, WS: ws}
+}
+
+// GetPresence mengembalikan status online / terakhir dilihat seorang user.
+func (c *UserController) GetPresence(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	presence, err := c.WS.GetPresence(ctx.Request.Context(), userId, ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			utils.NotFound(ctx, "User tidak ditemukan", err)
+			return
+		}
+		utils.InternalError(ctx, "Gagal mengambil status user", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Status user ditemukan", presence)
+}
+
+// BlockUser memblokir user lain. User yang diblokir tidak diberi tahu.
+func (c *UserController) BlockUser(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	targetId := ctx.Param("id")
+
+	if targetId == userId {
+		utils.BadRequest(ctx, "Tidak bisa memblokir diri sendiri", nil)
+		return
+	}
+	if _, err := c.UserRepo.FindByID(ctx.Request.Context(), targetId); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			utils.NotFound(ctx, "User tidak ditemukan", err)
+			return
+		}
+		utils.InternalError(ctx, "Gagal memblokir user", err)
+		return
+	}
+
+	if _, err := c.UserRepo.BlockUser(ctx.Request.Context(), userId, targetId); err != nil {
+		utils.InternalError(ctx, "Gagal memblokir user", err)
+		return
+	}
+
+	// Yang diblokir langsung berhenti menerima update presence kita
+	c.WS.RevokePresence(userId, targetId)
+
+	utils.SuccessResponse(ctx, "User diblokir", gin.H{"userId": targetId, "blocked": true})
+}
+
+// UnblockUser membuka blokir user.
+func (c *UserController) UnblockUser(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+	targetId := ctx.Param("id")
+
+	if err := c.UserRepo.UnblockUser(ctx.Request.Context(), userId, targetId); err != nil {
+		utils.InternalError(ctx, "Gagal membuka blokir user", err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Blokir user dibuka", gin.H{"userId": targetId, "blocked": false})
+}
+
+// GetBlockedUsers mengambil daftar user yang diblokir.
+func (c *UserController) GetBlockedUsers(ctx *gin.Context) {
+	userId := ctx.GetString("userID")
+
+	blocks, err := c.UserRepo.GetBlockedUsers(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.InternalError(ctx, "Gagal mengambil daftar blokir", err)
+		return
+	}
+
+	res := make([]models.BlockedUserDTO, len(blocks))
+	for i, b := range blocks {
+		user := b.Blocked()
+		res[i] = models.BlockedUserDTO{
+			UserID:    b.BlockedID,
+			Name:      user.Name,
+			BlockedAt: b.CreatedAt,
+		}
+		if v, ok := user.AvatarURL(); ok {
+			res[i].AvatarUrl = v
+		}
+	}
+	utils.SuccessResponse(ctx, "Daftar user yang diblokir", res)
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"chat-app-be/config"
+	"chat-app-be/models"
 	"chat-app-be/prisma/db"
 	"chat-app-be/repositories"
 	"chat-app-be/utils"
@@ -10,9 +12,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 )
@@ -34,27 +41,119 @@ type Client struct {
 
 // WSMessage adalah struktur pesan yang dikirim/diterima via WebSocket.
 type WSMessage struct {
-	Type    string      `json:"type"`    // Jenis pesan: "chat", "status_update", dll.
-	ChatID  string      `json:"chatId"`  // ID tujuan (untuk chat)
-	Content string      `json:"content"` // Isi pesan
-	Data    interface{} `json:"data"`    // Data tambahan (opsional)
+	Type      string      `json:"type"`                // Jenis pesan: "chat", "status_update", dll.
+	ChatID    string      `json:"chatId"`              // ID tujuan (untuk chat)
+	Content   string      `json:"content"`             // Isi pesan
+	ReplyToID string      `json:"replyToId,omitempty"` // ID pesan yang dibalas (opsional)
+	Data      interface{} `json:"data"`                // Data tambahan (opsional)
 }
 
 // WSController mengelola semua koneksi WebSocket yang aktif dan integrasi database.
 type WSController struct {
-	Clients  map[string]*Client
-	ChatRepo *repositories.ChatRepository
-	mu       sync.Mutex
+	// Clients menyimpan semua socket aktif per user (satu user bisa tersambung dari beberapa perangkat)
+	Clients     map[string]map[*Client]bool
+	ChatRepo    *repositories.ChatRepository
+	UserRepo    *repositories.UserRepository
+	ContactRepo *repositories.ContactRepository
+	MediaRepo   *repositories.MediaRepository
+	mu          sync.Mutex
+
+	// presenceSubs: targetUserID -> subscriber yang ingin tahu perubahan online/offline target
+	presenceSubs map[string]map[string]bool
+
+	// typing: status mengetik per user+chat untuk throttle & timeout otomatis
+	typing   map[string]*typingState
+	typingMu sync.Mutex
+
+	// livePoints: waktu titik lokasi terkini terakhir yang diterima per user+chat (rate limit)
+	livePoints   map[string]time.Time
+	livePointsMu sync.Mutex
+
+	// previews: pengambil preview link; previewSem membatasi jumlah fetch yang berjalan bersamaan
+	previews   *utils.LinkPreviewFetcher
+	previewTTL time.Duration
+	previewSem chan struct{}
 }
 
-// NewWSController inisialisasi controller dengan repository chat.
-func NewWSController(chatRepo *repositories.ChatRepository) *WSController {
+// NewWSController inisialisasi controller dengan repository chat, user, dan kontak.
+func NewWSController(chatRepo *repositories.ChatRepository, userRepo *repositories.UserRepository, contactRepo *repositories.ContactRepository, mediaRepo *repositories.MediaRepository) *WSController {
 	return &WSController{
-		Clients:  make(map[string]*Client),
-		ChatRepo: chatRepo,
+		Clients:      make(map[string]map[*Client]bool),
+		ChatRepo:     chatRepo,
+		UserRepo:     userRepo,
+		ContactRepo:  contactRepo,
+		MediaRepo:    mediaRepo,
+		presenceSubs: make(map[string]map[string]bool),
+		typing:       make(map[string]*typingState),
+		livePoints:   make(map[string]time.Time),
+		previews:     newLinkPreviewFetcher(),
+		previewTTL:   linkPreviewCacheTTL(),
+		previewSem:   make(chan struct{}, linkPreviewConcurrency),
+	}
+}
+
+// sendToUser mengirim payload ke semua socket milik user tanpa memblokir.
+// Pemanggil wajib memegang ctrl.mu. Mengembalikan true jika minimal satu socket menerima payload;
+// false jika user tidak online atau buffer semua socketnya penuh (frame dibuang).
+func (ctrl *WSController) sendToUser(userID string, payload []byte) bool {
+	sent := false
+	for client := range ctrl.Clients[userID] {
+		select {
+		case client.Send <- payload:
+			sent = true
+		default:
+			log.Printf("[WS] Gagal kirim ke %s (buf penuh)", userID)
+		}
+	}
+	return sent
+}
+
+// register mendaftarkan socket baru; true jika ini socket pertama user (baru online)
+func (ctrl *WSController) register(client *Client) bool {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	sockets, ok := ctrl.Clients[client.UserID]
+	if !ok {
+		sockets = make(map[*Client]bool)
+		ctrl.Clients[client.UserID] = sockets
+	}
+	sockets[client] = true
+	return len(sockets) == 1
+}
+
+// unregister melepas socket; true jika ini socket terakhir user (jadi offline)
+func (ctrl *WSController) unregister(client *Client) bool {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	sockets, ok := ctrl.Clients[client.UserID]
+	if !ok || !sockets[client] {
+		return false
+	}
+	delete(sockets, client)
+	if len(sockets) > 0 {
+		return false
+	}
+	delete(ctrl.Clients, client.UserID)
+	return true
+}
+
+// DisconnectUser menutup semua socket milik user (mis. akun ditangguhkan).
+// Pembersihan registrasi & presence tetap dijalankan readPump saat koneksinya terputus.
+func (ctrl *WSController) DisconnectUser(userID string) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for client := range ctrl.Clients[userID] {
+		client.Conn.Close()
 	}
 }
 
+// IsOnline mengecek apakah user punya minimal satu socket aktif
+func (ctrl *WSController) IsOnline(userID string) bool {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	return len(ctrl.Clients[userID]) > 0
+}
+
 // HandleWS adalah endpoint utama untuk upgrade koneksi ke WebSocket dengan validasi token.
 func (ctrl *WSController) HandleWS(ctx *gin.Context) {
 	userId := ctx.Query("userId")
@@ -84,6 +183,17 @@ func (ctrl *WSController) HandleWS(ctx *gin.Context) {
 		return
 	}
 
+	// Token lama milik akun yang ditangguhkan tidak boleh membuka socket baru
+	user, err := ctrl.UserRepo.FindByID(ctx.Request.Context(), userId)
+	if err != nil {
+		utils.Unauthorized(ctx, "Akun tidak ditemukan")
+		return
+	}
+	if until, suspended := repositories.ActiveSuspension(user); suspended {
+		utils.Forbidden(ctx, "Akun Anda ditangguhkan sampai "+until.Format(time.RFC3339), nil)
+		return
+	}
+
 	// 2. Upgrade ke WebSocket
 	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
 	if err != nil {
@@ -97,25 +207,124 @@ func (ctrl *WSController) HandleWS(ctx *gin.Context) {
 		Send:   make(chan []byte, 256),
 	}
 
-	ctrl.mu.Lock()
-	ctrl.Clients[userId] = client
-	ctrl.mu.Unlock()
+	firstSocket := ctrl.register(client)
 
 	log.Printf("[WS] User %s terhubung dengan aman", userId)
 
 	// Jalankan goroutine untuk baca dan tulis pesan secara konkuren
 	go client.writePump()
 	go client.readPump(ctrl)
+
+	// Socket pertama = user baru online
+	if firstSocket {
+		go ctrl.setPresence(userId, true)
+	}
+
+	// Kirim pesan yang tertunda selama user offline lalu tandai DELIVERED
+	go ctrl.flushPending(client)
+}
+
+// pendingFlushLimit batas pesan tertunda per chat yang dikirim ulang saat reconnect;
+// sisanya diambil client lewat riwayat chat (REST)
+const pendingFlushLimit = 100
+
+// flushPending mengirim pesan yang belum sampai ke user saat ia tersambung kembali,
+// lalu memajukan cursor terima-nya per chat.
+func (ctrl *WSController) flushPending(client *Client) {
+	ctx := context.Background()
+	pending, err := ctrl.ChatRepo.GetPendingDeliveries(ctx, client.UserID, pendingFlushLimit)
+	if err != nil {
+		log.Println("[WS] Gagal mengambil pesan tertunda:", err)
+		return
+	}
+
+	for chatID, messages := range pending {
+		for _, m := range messages {
+			payload, _ := json.Marshal(WSMessage{
+				Type:    messageEvent(m),
+				ChatID:  chatID,
+				Content: m.Content,
+				Data:    toMessageDTO(m),
+			})
+			if !ctrl.deliver(client, payload) {
+				return
+			}
+		}
+		last := messages[len(messages)-1]
+		if err := ctrl.MarkDelivered(ctx, client.UserID, chatID, last.ID); err != nil {
+			log.Println("[WS] Gagal menandai pesan tertunda DELIVERED:", err)
+		}
+	}
+}
+
+// deliver mengirim payload ke socket client jika client masih terdaftar (false jika sudah terputus/penuh)
+func (ctrl *WSController) deliver(client *Client, payload []byte) bool {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	if !ctrl.Clients[client.UserID][client] {
+		return false
+	}
+	select {
+	case client.Send <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarkDelivered memajukan cursor terima user lalu memberi tahu pengirim lewat "status_update":
+// per penerima (centang dua per anggota di grup) dan per pesan saat sudah sampai ke semua penerima.
+func (ctrl *WSController) MarkDelivered(ctx context.Context, userID, chatID, messageID string) error {
+	delivered, flipped, err := ctrl.ChatRepo.MarkChatDelivered(ctx, chatID, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	// Kelompokkan pesan yang baru sampai per pengirim
+	bySender := make(map[string][]string)
+	for _, m := range delivered {
+		bySender[m.SenderID] = append(bySender[m.SenderID], m.ID)
+	}
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for senderID, ids := range bySender {
+		payload, _ := json.Marshal(WSMessage{
+			Type:    "status_update",
+			ChatID:  chatID,
+			Content: "Pesan telah diterima",
+			Data: gin.H{
+				"userId":     userID,
+				"status":     db.MessageStatusDelivered,
+				"messageIds": ids,
+			},
+		})
+		ctrl.sendToUser(senderID, payload)
+	}
+	for _, m := range flipped {
+		payload, _ := json.Marshal(WSMessage{
+			Type:    "status_update",
+			ChatID:  chatID,
+			Content: "Pesan telah diterima semua penerima",
+			Data:    m,
+		})
+		ctrl.sendToUser(m.SenderID, payload)
+	}
+	return nil
 }
 
 // readPump mendengarkan pesan masuk dari client.
 func (c *Client) readPump(ctrl *WSController) {
 	defer func() {
-		ctrl.mu.Lock()
-		delete(ctrl.Clients, c.UserID)
-		ctrl.mu.Unlock()
+		lastSocket := ctrl.unregister(c)
 		c.Conn.Close()
 		log.Printf("[WS] User %s terputus", c.UserID)
+
+		// Socket terakhir putus = user offline
+		if lastSocket {
+			ctrl.stopAllTyping(c.UserID)
+			ctrl.setPresence(c.UserID, false)
+		}
 	}()
 
 	for {
@@ -134,8 +343,20 @@ func (c *Client) readPump(ctrl *WSController) {
 		switch msg.Type {
 		case "chat":
 			ctrl.handleChatMessage(c.UserID, msg)
-		case "read_receipt":
+		case "mark_read", "read_receipt":
 			ctrl.handleReadReceipt(c.UserID, msg)
+		case "reaction_add", "reaction_remove":
+			ctrl.handleReaction(c.UserID, msg)
+		case "poll_vote":
+			ctrl.handlePollVote(c.UserID, msg)
+		case "typing_start", "typing_stop":
+			ctrl.handleTyping(c.UserID, msg)
+		case "presence_subscribe", "presence_unsubscribe":
+			ctrl.handlePresenceSubscription(c.UserID, msg)
+		case "live_location_update", "live_location_stop":
+			ctrl.handleLiveLocation(c.UserID, msg)
+		case "draft_update":
+			ctrl.handleDraft(c, msg)
 		default:
 			log.Printf("[WS] Tipe pesan tidak dikenal: %s", msg.Type)
 		}
@@ -153,145 +374,1559 @@ func (c *Client) writePump() {
 	}
 }
 
-// handleChatMessage menyimpan pesan ke DB dan mengirimkannya ke peserta lain yang online.
+// handleChatMessage menerima pesan chat dari socket lalu meneruskannya ke SendMessage.
 func (ctrl *WSController) handleChatMessage(senderID string, msg WSMessage) {
-	// 1. Simpan pesan ke database secara permanen
-	newMsg, err := ctrl.ChatRepo.CreateMessage(context.Background(), senderID, msg.ChatID, msg.Content, db.MessageTypeText)
-	if err != nil {
-		log.Println("[WS] Gagal simpan pesan ke DB:", err)
+	// "data" berbentuk sama dengan body REST kirim pesan (type, threadRootId, location, contact, audio);
+	// content & replyToId di field utama tetap dipakai jika diisi
+	var input models.SendMessageDTO
+	if msg.Data != nil {
+		raw, _ := json.Marshal(msg.Data)
+		if err := json.Unmarshal(raw, &input); err != nil {
+			log.Println("[WS] Data pesan tidak valid")
+			ctrl.sendError(senderID, msg.ChatID, err)
+			return
+		}
+	}
+	if msg.Content != "" {
+		input.Content = msg.Content
+	}
+	if msg.ReplyToID != "" {
+		input.ReplyToID = msg.ReplyToID
+	}
+	// Validasi sama dengan binding JSON di REST
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		ctrl.sendError(senderID, msg.ChatID, err)
 		return
 	}
 
-	// 2. Siapkan payload untuk dikirim ke peserta chat (Event Real-time Chat)
-	payloadChat, _ := json.Marshal(WSMessage{
-		Type:    "chat",
-		ChatID:  msg.ChatID,
-		Content: msg.Content,
-		Data:    newMsg,
-	})
+	if _, err := ctrl.SendMessage(context.Background(), senderID, msg.ChatID, input); err != nil {
+		log.Println("[WS] Gagal kirim pesan:", err)
+		ctrl.sendError(senderID, msg.ChatID, err)
+		return
+	}
+	// Balasan thread dikirim dari panel thread, draft chat utama tidak disentuh
+	if input.ThreadRootID == "" {
+		ctrl.clearDraft(senderID, msg.ChatID)
+	}
+}
 
-	// 3. Siapkan payload untuk Notifikasi Real-time
-	payloadNotif, _ := json.Marshal(WSMessage{
-		Type:    "notification",
-		ChatID:  msg.ChatID,
-		Content: "Pesan baru: " + msg.Content,
-		Data:    newMsg,
-	})
+// draftMaxLength batas panjang isi draft (karakter)
+const draftMaxLength = 4096
 
-	// 4. Ambil daftar peserta chat dari DB
-	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
-		db.Participant.ChatID.Equals(msg.ChatID),
-	).Exec(context.Background())
+// handleDraft menyimpan draft yang dikirim lewat socket; perangkat lain milik user ikut diperbarui.
+func (ctrl *WSController) handleDraft(c *Client, msg WSMessage) {
+	if msg.ChatID == "" {
+		return
+	}
+	input := models.SaveDraftDTO{Content: msg.Content, ReplyToID: msg.ReplyToID}
 
-	// 5. Kirim ke semua peserta yang online
-	ctrl.mu.Lock()
-	defer ctrl.mu.Unlock()
-	for _, p := range participants {
-		if client, ok := ctrl.Clients[p.UserID]; ok {
-			// Kirim data chat utama
-			client.Send <- payloadChat
-			
-			// Jika bukan pengirim, kirim juga notifikasi
-			if p.UserID != senderID {
-				client.Send <- payloadNotif
-			}
-		}
+	if _, err := ctrl.SaveDraft(context.Background(), c.UserID, msg.ChatID, input, c); err != nil {
+		log.Println("[WS] Gagal menyimpan draft:", err)
+		ctrl.sendError(c.UserID, msg.ChatID, err)
 	}
 }
 
-// handleReadReceipt memproses status pesan yang telah dibaca oleh penerima.
-func (ctrl *WSController) handleReadReceipt(_ string, msg WSMessage) {
-	messageID, ok := msg.Data.(string)
-	if !ok {
-		log.Println("[WS] ID pesan tidak valid dalam read_receipt")
-		return
+// SaveDraft memvalidasi dan menyimpan draft user di chat, lalu mengirim "draft_updated"
+// ke semua socket user kecuali origin (nil = semua socket, mis. dari REST).
+// Mengembalikan nil jika draft dihapus.
+func (ctrl *WSController) SaveDraft(ctx context.Context, userID, chatID string, input models.SaveDraftDTO, origin *Client) (*models.DraftDTO, error) {
+	if utf8.RuneCountInString(input.Content) > draftMaxLength {
+		return nil, repositories.ErrDraftTooLong
+	}
+	if !ctrl.ChatRepo.IsParticipant(ctx, chatID, userID) {
+		return nil, repositories.ErrNotParticipant
 	}
 
-	// 1. Update status di database menjadi READ
-	updatedMsg, err := ctrl.ChatRepo.UpdateMessageStatus(context.Background(), messageID, db.MessageStatusRead)
+	content := input.Content
+	if strings.TrimSpace(content) == "" {
+		content = ""
+	}
+	if input.ReplyToID != "" {
+		if _, err := ctrl.replyTarget(ctx, chatID, userID, input.ReplyToID); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := ctrl.ChatRepo.SaveDraft(ctx, chatID, userID, content, input.ReplyToID)
 	if err != nil {
-		log.Println("[WS] Gagal update status READ di DB:", err)
-		return
+		return nil, err
 	}
 
-	// 2. Beritahu pengirim pesan bahwa pesan mereka sudah dibaca (Centang Biru)
-	payload, _ := json.Marshal(WSMessage{
-		Type:    "status_update",
-		ChatID:  msg.ChatID,
-		Content: "Pesan telah dibaca",
-		Data:    updatedMsg,
-	})
+	draft := toDraftDTO(*updated)
+	ctrl.syncDraft(userID, chatID, draft, origin)
+	return draft, nil
+}
 
-	ctrl.mu.Lock()
-	defer ctrl.mu.Unlock()
-	if client, ok := ctrl.Clients[updatedMsg.SenderID]; ok {
-		client.Send <- payload
+// replyTarget memvalidasi pesan yang akan dibalas (saat kirim maupun di draft): harus ada di chat
+// yang sama, terlihat oleh user (pesan yang di-drop karena blokir hanya terlihat pengirimnya),
+// dan belum dihapus
+func (ctrl *WSController) replyTarget(ctx context.Context, chatID, userID, messageID string) (*db.MessageModel, error) {
+	target, err := ctrl.ChatRepo.FindMessageInChat(ctx, chatID, messageID)
+	if err != nil {
+		return nil, err
 	}
+	if target.DroppedByBlock && target.SenderID != userID {
+		return nil, repositories.ErrMessageNotInChat
+	}
+	if target.IsDeleted {
+		return nil, repositories.ErrMessageDeleted
+	}
+	return target, nil
 }
 
-// NotifyStatusUpdate memberitahu semua user yang online tentang status baru (Global Notification).
-func (ctrl *WSController) NotifyStatusUpdate(ownerName string, statusData interface{}) {
+// clearDraft menghapus draft setelah user mengirim pesan dari perangkat mana pun
+// dan mengabarkan semua socket user jika sebelumnya ada draft.
+func (ctrl *WSController) clearDraft(userID, chatID string) {
+	cleared, err := ctrl.ChatRepo.ClearDraft(context.Background(), chatID, userID)
+	if err != nil {
+		log.Println("[WS] Gagal menghapus draft:", err)
+		return
+	}
+	if cleared {
+		ctrl.syncDraft(userID, chatID, nil, nil)
+	}
+}
+
+// syncDraft mengirim event "draft_updated" ke socket milik user selain origin.
+// Data nil berarti draft sudah dihapus.
+func (ctrl *WSController) syncDraft(userID, chatID string, draft *models.DraftDTO, origin *Client) {
 	payload, _ := json.Marshal(WSMessage{
-		Type:    "status_update",
-		Content: ownerName + " baru saja mengunggah status baru!",
-		Data:    statusData,
+		Type:   "draft_updated",
+		ChatID: chatID,
+		Data:   draft,
 	})
 
 	ctrl.mu.Lock()
 	defer ctrl.mu.Unlock()
-	for _, client := range ctrl.Clients {
+	for client := range ctrl.Clients[userID] {
+		if client == origin {
+			continue
+		}
 		select {
 		case client.Send <- payload:
 		default:
-			log.Printf("[WS] Gagal kirim notif status ke %s (buf penuh)", client.UserID)
+			log.Printf("[WS] Gagal kirim ke %s (buf penuh)", userID)
 		}
 	}
 }
 
-// NotifyUser mengirimkan notifikasi spesifik ke satu user (User-to-User Notification).
-func (ctrl *WSController) NotifyUser(targetUserID string, subType string, message string, data interface{}) {
-	payload, _ := json.Marshal(WSMessage{
-		Type:    "notification",
-		Content: message,
-		Data:    data,
-	})
+// directBlockPolicy menentukan nasib pesan di chat direct yang terkena blokir: yang memblokir harus
+// membuka blokir dulu, sedangkan pesan dari user yang diblokir di-drop diam-diam (tersimpan tapi
+// hanya terlihat oleh pengirimnya)
+func directBlockPolicy(senderBlocksPeer, peerBlocksSender bool) (dropped bool, err error) {
+	if senderBlocksPeer {
+		return false, repositories.ErrBlockingUser
+	}
+	return peerBlocksSender, nil
+}
 
-	ctrl.mu.Lock()
-	defer ctrl.mu.Unlock()
-	if client, ok := ctrl.Clients[targetUserID]; ok {
-		select {
-		case client.Send <- payload:
-		default:
-			log.Printf("[WS] Gagal kirim notif ke %s (buf penuh)", targetUserID)
+// directBlockState menerapkan directBlockPolicy pada keanggotaan pengirim (hasil CheckPermission).
+// Grup tidak terkena aturan ini.
+func (ctrl *WSController) directBlockState(ctx context.Context, participant *db.ParticipantModel, senderID string) (bool, error) {
+	if participant.Chat().IsGroup {
+		return false, nil
+	}
+	peerID, ok := ctrl.ChatRepo.GetDirectPeerID(ctx, participant.ChatID, senderID)
+	if !ok {
+		return false, nil
+	}
+	return directBlockPolicy(ctrl.ChatRepo.IsBlocked(ctx, senderID, peerID), ctrl.ChatRepo.IsBlocked(ctx, peerID, senderID))
+}
+
+// SendMessage memvalidasi, menyimpan, lalu menyebarkan pesan baru ke peserta chat.
+// Dipakai bersama oleh jalur WebSocket dan REST supaya aturan validasinya sama persis.
+// extras dipakai untuk field internal yang tidak datang dari input client (mis. penanda forward).
+func (ctrl *WSController) SendMessage(ctx context.Context, senderID, chatID string, input models.SendMessageDTO, extras ...db.MessageSetParam) (*db.MessageModel, error) {
+	// 1. Pengirim wajib peserta chat dan diizinkan mengirim (mode pengumuman grup)
+	participant, err := ctrl.ChatRepo.CheckPermission(ctx, chatID, senderID, repositories.ActionSend)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chat direct dengan blokir (lihat directBlockPolicy)
+	dropped, err := ctrl.directBlockState(ctx, participant, senderID)
+	if err != nil {
+		return nil, err
+	}
+	if dropped {
+		extras = append(extras, db.Message.DroppedByBlock.Set(true))
+	}
+
+	msgType := db.MessageTypeText
+	if input.Type != "" {
+		msgType = db.MessageType(input.Type)
+	}
+
+	// Tipe terstruktur disimpan sebagai payload JSON; content diisi teks fallback (pencarian & client lama)
+	content := input.Content
+	switch msgType {
+	case db.MessageTypeLocation, db.MessageTypeContact, db.MessageTypeAudio:
+		payload, fallback, err := ctrl.buildPayload(ctx, senderID, msgType, input)
+		if err != nil {
+			return nil, err
+		}
+		content = fallback
+		extras = append(extras, db.Message.Payload.Set(payload))
+	default:
+		if strings.TrimSpace(content) == "" {
+			return nil, repositories.ErrEmptyContent
 		}
 	}
+
+	// 2. Reply hanya boleh ke pesan di chat yang sama yang terlihat oleh pengirim
+	if input.ReplyToID != "" {
+		if _, err := ctrl.replyTarget(ctx, chatID, senderID, input.ReplyToID); err != nil {
+			return nil, err
+		}
+		extras = append(extras, db.Message.ReplyTo.Link(db.Message.ID.Equals(input.ReplyToID)))
+	}
+
+	// Balasan thread: root harus pesan di chat yang sama (balasan di dalam thread diarahkan ke root-nya)
+	var threadRoot *db.MessageModel
+	if input.ThreadRootID != "" {
+		root, err := ctrl.ChatRepo.ResolveThreadRoot(ctx, chatID, input.ThreadRootID)
+		if err != nil {
+			return nil, err
+		}
+		threadRoot = root
+		extras = append(extras, db.Message.ThreadRoot.Link(db.Message.ID.Equals(root.ID)))
+	}
+
+	// Mention: hanya anggota chat yang valid yang disimpan (@all khusus grup)
+	var mentionIDs []string
+	if msgType == db.MessageTypeText && !dropped {
+		usernames, all := utils.ParseMentions(input.Content)
+		all = all && participant.Chat().IsGroup
+		if mentionIDs, err = ctrl.ChatRepo.ResolveMentions(ctx, chatID, senderID, usernames, all); err != nil {
+			return nil, err
+		}
+		if all {
+			extras = append(extras, db.Message.MentionsAll.Set(true))
+		}
+	}
+
+	// 3. Simpan pesan ke database secara permanen
+	created, err := ctrl.ChatRepo.CreateMessage(ctx, senderID, chatID, content, msgType, extras...)
+	if err != nil {
+		return nil, err
+	}
+	if threadRoot != nil && !dropped {
+		if err := ctrl.ChatRepo.RecordThreadReply(ctx, threadRoot, created); err != nil {
+			log.Println("[WS] Gagal memperbarui ringkasan thread:", err)
+		}
+	}
+	if err := ctrl.ChatRepo.SaveMentions(ctx, created.ID, mentionIDs); err != nil {
+		log.Println("[WS] Gagal menyimpan mention:", err)
+	}
+	// File yang diunggah pengirim untuk pesan ini boleh ikut dihapus saat pesan kedaluwarsa
+	if msgType == db.MessageTypeImage || msgType == db.MessageTypeVideo || msgType == db.MessageTypeDocument || msgType == db.MessageTypeAudio {
+		if err := ctrl.MediaRepo.AttachToMessage(ctx, created.ID, senderID, created.Content); err != nil {
+			log.Println("[WS] Gagal menautkan media ke pesan:", err)
+		}
+	}
+
+	// 4. Chat yang diarsipkan anggota muncul lagi (kecuali yang memilih tetap arsip)
+	if !dropped {
+		if err := ctrl.ChatRepo.UnarchiveOnNewMessage(ctx, chatID); err != nil {
+			log.Println("[WS] Gagal membuka arsip chat:", err)
+		}
+	}
+
+	// 5. Ambil ulang lengkap dengan preview reply, lalu sebarkan
+	newMsg, err := ctrl.ChatRepo.GetMessageByID(ctx, created.ID)
+	if err != nil {
+		// Tetap sebarkan pesan yang baru dibuat; mention diisi dari hasil resolve agar notifikasinya tidak hilang
+		newMsg = created
+		for _, id := range mentionIDs {
+			newMsg.RelationsMessage.Mentions = append(newMsg.RelationsMessage.Mentions, db.MessageMentionModel{
+				InnerMessageMention: db.InnerMessageMention{MessageID: created.ID, UserID: id},
+			})
+		}
+	}
+	ctrl.BroadcastMessage(senderID, newMsg)
+
+	// 6. Preview link diambil di belakang layar, pengirim tidak perlu menunggu
+	if msgType == db.MessageTypeText {
+		go ctrl.unfurlLink(newMsg)
+	}
+	return newMsg, nil
 }
 
-// NotifyGroup memberitahu semua anggota grup kecuali pengirim (Group Event Notification).
-func (ctrl *WSController) NotifyGroup(chatID string, excludeUserID string, message string, data interface{}) {
-	// Ambil daftar peserta chat dari DB
-	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
-		db.Participant.ChatID.Equals(chatID),
-	).Exec(context.Background())
+// buildPayload memvalidasi data terstruktur sesuai tipe pesan, lalu mengembalikan JSON untuk kolom
+// payload beserta teks fallback untuk content. Kartu kontak user aplikasi dilengkapi dari profilnya.
+func (ctrl *WSController) buildPayload(ctx context.Context, senderID string, msgType db.MessageType, input models.SendMessageDTO) (db.JSON, string, error) {
+	var payload interface{}
+	var fallback string
+	switch msgType {
+	case db.MessageTypeLocation:
+		if input.Location == nil || input.Location.Latitude == nil || input.Location.Longitude == nil {
+			return nil, "", repositories.ErrPayloadRequired
+		}
+		payload = input.Location
+		fallback = joinNonEmpty(", ", input.Location.Label, input.Location.Address)
 
-	payload, _ := json.Marshal(WSMessage{
-		Type:    "notification",
-		Content: message,
-		Data:    data,
-	})
+	case db.MessageTypeContact:
+		if input.Contact == nil {
+			return nil, "", repositories.ErrPayloadRequired
+		}
+		contact := *input.Contact
+		if contact.UserID != "" {
+			// User yang saling blokir dengan pengirim diperlakukan seperti tidak ada, supaya
+			// kartu kontak tidak bisa dipakai untuk mengintip profil orang yang memblokir
+			if ctrl.ChatRepo.IsBlockedEitherWay(ctx, senderID, contact.UserID) {
+				return nil, "", db.ErrNotFound
+			}
+			user, err := ctrl.UserRepo.FindByID(ctx, contact.UserID)
+			if err != nil {
+				return nil, "", err
+			}
+			if contact.Name == "" {
+				contact.Name = user.Name
+			}
+		}
+		if contact.Name == "" {
+			return nil, "", repositories.ErrPayloadRequired
+		}
+		payload = contact
+		parts := append([]string{contact.Name, contact.Organization}, contact.Phones...)
+		fallback = joinNonEmpty(" ", append(parts, contact.Emails...)...)
 
-	ctrl.mu.Lock()
-	defer ctrl.mu.Unlock()
-	for _, p := range participants {
-		// Jangan kirim ke diri sendiri jika sedang aktif di thread ini
-		if p.UserID == excludeUserID {
+	case db.MessageTypeAudio:
+		if input.Audio == nil || input.Audio.URL == "" {
+			return nil, "", repositories.ErrPayloadRequired
+		}
+		// Hanya file hasil upload ke Cloudinary aplikasi ini yang diterima
+		if !config.IsOwnMediaURL(input.Audio.URL) {
+			return nil, "", repositories.ErrForeignMediaURL
+		}
+		payload = input.Audio
+		// Seperti media lain, content berisi URL file (dipakai sweeper pesan sementara)
+		fallback = input.Audio.URL
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+	return db.JSON(raw), fallback, nil
+}
+
+// joinNonEmpty menggabungkan teks yang tidak kosong dengan pemisah sep
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// ForwardMessage meneruskan salinan pesan ke beberapa chat sekaligus.
+// User wajib peserta chat asal dan SEMUA chat tujuan sebelum ada yang dikirim.
+func (ctrl *WSController) ForwardMessage(ctx context.Context, userID, messageID string, chatIDs []string) ([]*db.MessageModel, error) {
+	// 1. Validasi pesan sumber
+	src, err := ctrl.ChatRepo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !ctrl.ChatRepo.IsParticipant(ctx, src.ChatID, userID) {
+		return nil, repositories.ErrNotParticipant
+	}
+	if src.IsDeleted {
+		return nil, repositories.ErrMessageDeleted
+	}
+	if src.Type == db.MessageTypeInfo || src.Type == db.MessageTypePoll {
+		return nil, repositories.ErrNotForwardable
+	}
+
+	input := models.SendMessageDTO{
+		Content: src.Content,
+		Type:    string(src.Type),
+	}
+	input.Location, input.Contact, input.Audio = decodePayload(*src)
+	// Payload tidak bergantung pada chat tujuan, jadi cukup divalidasi sekali di depan
+	switch src.Type {
+	case db.MessageTypeLocation, db.MessageTypeContact, db.MessageTypeAudio:
+		if _, _, err := ctrl.buildPayload(ctx, userID, src.Type, input); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Validasi semua chat tujuan (buang duplikat), termasuk blokir di chat direct,
+	// supaya tidak ada salinan yang terkirim sebelum ketahuan ada tujuan yang ditolak
+	seen := make(map[string]bool)
+	var targets []string
+	for _, chatID := range chatIDs {
+		if seen[chatID] {
 			continue
 		}
-		if client, ok := ctrl.Clients[p.UserID]; ok {
-			select {
-			case client.Send <- payload:
-			default:
-			}
+		seen[chatID] = true
+		participant, err := ctrl.ChatRepo.CheckPermission(ctx, chatID, userID, repositories.ActionSend)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := ctrl.directBlockState(ctx, participant, userID); err != nil {
+			return nil, err
+		}
+		targets = append(targets, chatID)
+	}
+
+	// 3. Kirim salinan lewat jalur normal. Konten media cukup menyalin URL-nya,
+	// jadi tidak perlu upload ulang lewat MediaController.
+	var copies []*db.MessageModel
+	for _, chatID := range targets {
+		copyMsg, err := ctrl.SendMessage(ctx, userID, chatID, input,
+			db.Message.IsForwarded.Set(true),
+			db.Message.ForwardCount.Set(src.ForwardCount+1),
+		)
+		if err != nil {
+			return copies, err
 		}
+		copies = append(copies, copyMsg)
+	}
+	return copies, nil
+}
+
+// messageEvent menentukan tipe event WS untuk pesan baru. Balasan thread dikirim sebagai
+// "thread_reply" karena tidak termasuk timeline utama (sama dengan GetChatMessages).
+func messageEvent(m db.MessageModel) string {
+	if _, ok := m.ThreadRootID(); ok {
+		return "thread_reply"
+	}
+	return "chat"
+}
+
+// BroadcastMessage mengirim event "chat" (atau "thread_reply") ke semua peserta yang online
+// dan event "notification" ke peserta selain pengirim.
+// Untuk balasan thread, notifikasi hanya untuk pengikut thread dan ringkasan root ikut dikirim ("thread_updated").
+func (ctrl *WSController) BroadcastMessage(senderID string, newMsg *db.MessageModel) {
+	dto := toMessageDTO(*newMsg)
+
+	// 1. Siapkan payload untuk dikirim ke peserta chat (Event Real-time Chat)
+	payloadChat, _ := json.Marshal(WSMessage{
+		Type:    messageEvent(*newMsg),
+		ChatID:  newMsg.ChatID,
+		Content: newMsg.Content,
+		Data:    dto,
+	})
+
+	// Pesan yang di-drop karena blokir hanya dikembalikan ke pengirim, seolah terkirim biasa
+	if newMsg.DroppedByBlock {
+		ctrl.mu.Lock()
+		ctrl.sendToUser(senderID, payloadChat)
+		ctrl.mu.Unlock()
+		return
+	}
+
+	// 2. Siapkan payload untuk Notifikasi Real-time
+	payloadNotif, _ := json.Marshal(WSMessage{
+		Type:    "notification",
+		ChatID:  newMsg.ChatID,
+		Content: "Pesan baru: " + messagePreview(*newMsg),
+		Data:    dto,
+	})
+
+	// Notifikasi prioritas tinggi untuk user yang di-mention (tetap dikirim meski chat di-mute)
+	payloadMention, _ := json.Marshal(WSMessage{
+		Type:    "mention",
+		ChatID:  newMsg.ChatID,
+		Content: "Anda di-mention: " + newMsg.Content,
+		Data:    dto,
+	})
+	mentioned := make(map[string]bool)
+	for _, m := range newMsg.RelationsMessage.Mentions {
+		mentioned[m.UserID] = true
+	}
+
+	// 3. Ambil daftar peserta chat dari DB
+	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(newMsg.ChatID),
+	).Exec(context.Background())
+
+	// Balasan thread: siapkan root & pengikutnya
+	var threadRoot *db.MessageModel
+	var followers []db.ThreadFollowerModel
+	followerSet := make(map[string]bool)
+	if rootID, ok := newMsg.ThreadRootID(); ok {
+		threadRoot, _ = ctrl.ChatRepo.GetMessageByID(context.Background(), rootID)
+		followers, _ = ctrl.ChatRepo.GetThreadFollowers(context.Background(), rootID)
+		for _, f := range followers {
+			followerSet[f.UserID] = true
+		}
+	}
+
+	// 4. Kirim ke semua peserta yang online
+	now := time.Now()
+	var received []string
+	ctrl.mu.Lock()
+	for _, p := range participants {
+		// Kirim data chat utama
+		if !ctrl.sendToUser(p.UserID, payloadChat) {
+			continue
+		}
+
+		// Ringkasan thread (jumlah balasan, unread) berbeda per user
+		if threadRoot != nil {
+			payloadThread, _ := json.Marshal(WSMessage{
+				Type:   "thread_updated",
+				ChatID: newMsg.ChatID,
+				Data: gin.H{
+					"rootId": threadRoot.ID,
+					"thread": summarizeThread(*threadRoot, followers, p.UserID),
+				},
+			})
+			ctrl.sendToUser(p.UserID, payloadThread)
+		}
+
+		// Jika bukan pengirim, kirim juga notifikasi (kecuali chat di-mute penerima,
+		// atau balasan thread yang tidak diikuti). Mention selalu dikirim.
+		if p.UserID != senderID {
+			if mentioned[p.UserID] {
+				ctrl.sendToUser(p.UserID, payloadMention)
+			} else if !repositories.IsMuted(p, now) && (threadRoot == nil || followerSet[p.UserID]) {
+				ctrl.sendToUser(p.UserID, payloadNotif)
+			}
+			received = append(received, p.UserID)
+		}
+	}
+	ctrl.mu.Unlock()
+
+	// 5. Pesan sudah masuk ke socket penerima yang online -> DELIVERED (tanpa menahan pengirim)
+	go func() {
+		for _, userID := range received {
+			if err := ctrl.MarkDelivered(context.Background(), userID, newMsg.ChatID, newMsg.ID); err != nil {
+				log.Println("[WS] Gagal menandai pesan DELIVERED:", err)
+			}
+		}
+	}()
+}
+
+// handleReaction memproses reaksi dari socket: Content berisi emoji, Data berisi ID pesan.
+func (ctrl *WSController) handleReaction(userID string, msg WSMessage) {
+	messageID, ok := msg.Data.(string)
+	if !ok || messageID == "" || msg.Content == "" {
+		log.Println("[WS] Data reaksi tidak valid")
+		return
+	}
+
+	_, err := ctrl.ReactToMessage(context.Background(), userID, messageID, msg.Content, msg.Type == "reaction_add")
+	if err != nil {
+		log.Println("[WS] Gagal memproses reaksi:", err)
+		ctrl.sendError(userID, msg.ChatID, err)
+	}
+}
+
+// ReactToMessage menambah/menghapus reaksi, lalu menyebarkan agregat terbarunya ke peserta chat.
+// Mengembalikan agregat reaksi dari sudut pandang user yang bereaksi.
+func (ctrl *WSController) ReactToMessage(ctx context.Context, userID, messageID, emoji string, add bool) ([]models.ReactionDTO, error) {
+	// 1. Pastikan pesan ada dan user adalah peserta chat-nya
+	msg, err := ctrl.ChatRepo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !ctrl.ChatRepo.IsParticipant(ctx, msg.ChatID, userID) {
+		return nil, repositories.ErrNotParticipant
+	}
+	if add && msg.IsDeleted {
+		return nil, repositories.ErrMessageDeleted
+	}
+
+	// 2. Simpan perubahan reaksi
+	if add {
+		_, err = ctrl.ChatRepo.AddReaction(ctx, messageID, userID, emoji)
+	} else {
+		err = ctrl.ChatRepo.RemoveReaction(ctx, messageID, userID, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Sebarkan agregat terbaru ke semua peserta
+	reactions, err := ctrl.ChatRepo.GetMessageReactions(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	ctrl.broadcastReactions(msg.ChatID, messageID, reactions)
+
+	// 4. Beri tahu pengirim pesan (kecuali bereaksi ke pesan sendiri)
+	if add && msg.SenderID != userID {
+		name := "Seseorang"
+		if reactor, err := ctrl.ChatRepo.Client.User.FindUnique(db.User.ID.Equals(userID)).Exec(ctx); err == nil {
+			name = reactor.Name
+		}
+		go ctrl.NotifyUser(msg.SenderID, "reaction", name+" bereaksi "+emoji+" pada pesan Anda", gin.H{
+			"chatId":    msg.ChatID,
+			"messageId": messageID,
+			"userId":    userID,
+			"emoji":     emoji,
+		})
+	}
+
+	return aggregateReactions(reactions, userID), nil
+}
+
+// broadcastReactions mengirim event "reaction_updated" ke peserta chat yang online.
+// Payload dibuat per penerima karena flag reactedByMe berbeda untuk tiap user.
+func (ctrl *WSController) broadcastReactions(chatID, messageID string, reactions []db.MessageReactionModel) {
+	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatID),
+	).Exec(context.Background())
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for _, p := range participants {
+		if len(ctrl.Clients[p.UserID]) == 0 {
+			continue
+		}
+		payload, _ := json.Marshal(WSMessage{
+			Type:   "reaction_updated",
+			ChatID: chatID,
+			Data: gin.H{
+				"messageId": messageID,
+				"reactions": aggregateReactions(reactions, p.UserID),
+			},
+		})
+		ctrl.sendToUser(p.UserID, payload)
+	}
+}
+
+// CreatePoll menyimpan polling lalu mengirimnya sebagai pesan POLL lewat jalur kirim biasa.
+// Polling hanya tersedia di grup.
+func (ctrl *WSController) CreatePoll(ctx context.Context, senderID, chatID string, input models.CreatePollDTO) (*db.MessageModel, error) {
+	participant, err := ctrl.ChatRepo.CheckPermission(ctx, chatID, senderID, repositories.ActionSend)
+	if err != nil {
+		return nil, err
+	}
+	if !participant.Chat().IsGroup {
+		return nil, repositories.ErrNotGroup
+	}
+
+	poll, err := ctrl.ChatRepo.CreatePoll(ctx, input.Question, input.Options, input.AllowMultiple, input.IsAnonymous, input.ClosesAt)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := ctrl.SendMessage(ctx, senderID, chatID, models.SendMessageDTO{
+		Content: input.Question,
+		Type:    string(db.MessageTypePoll),
+	}, db.Message.Poll.Link(db.Poll.ID.Equals(poll.ID)))
+	if err != nil {
+		// Jangan tinggalkan polling tanpa pesan
+		_ = ctrl.ChatRepo.DeletePoll(ctx, poll.ID)
+		return nil, err
+	}
+	return msg, nil
+}
+
+// handlePollVote memproses suara polling dari socket: Data berisi {messageId, optionIds}.
+func (ctrl *WSController) handlePollVote(userID string, msg WSMessage) {
+	var input struct {
+		MessageID string   `json:"messageId"`
+		OptionIDs []string `json:"optionIds"`
+	}
+	raw, _ := json.Marshal(msg.Data)
+	if err := json.Unmarshal(raw, &input); err != nil || input.MessageID == "" {
+		log.Println("[WS] Data polling tidak valid")
+		return
+	}
+
+	if _, err := ctrl.VotePoll(context.Background(), userID, input.MessageID, input.OptionIDs); err != nil {
+		log.Println("[WS] Gagal memproses suara polling:", err)
+		ctrl.sendError(userID, msg.ChatID, err)
+	}
+}
+
+// VotePoll mengganti suara user pada polling, lalu menyebarkan hasil terbaru ke peserta chat.
+// Mengembalikan hasil dari sudut pandang user yang memilih.
+func (ctrl *WSController) VotePoll(ctx context.Context, userID, messageID string, optionIDs []string) (*models.PollDTO, error) {
+	msg, poll, err := ctrl.pollMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.IsDeleted {
+		return nil, repositories.ErrMessageDeleted
+	}
+
+	if err := ctrl.ChatRepo.VotePoll(ctx, poll, userID, optionIDs); err != nil {
+		return nil, err
+	}
+	return ctrl.refreshPoll(ctx, msg, userID)
+}
+
+// ClosePoll menutup polling. Hanya pembuat polling atau admin grup yang boleh.
+func (ctrl *WSController) ClosePoll(ctx context.Context, userID, messageID string) (*models.PollDTO, error) {
+	msg, poll, err := ctrl.pollMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		participant, err := ctrl.ChatRepo.GetParticipant(ctx, msg.ChatID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !repositories.IsGroupAdmin(participant.Role) {
+			return nil, repositories.ErrNotChatAdmin
+		}
+	}
+
+	if err := ctrl.ChatRepo.ClosePoll(ctx, poll.ID); err != nil {
+		return nil, err
+	}
+	return ctrl.refreshPoll(ctx, msg, userID)
+}
+
+// pollMessage mengambil pesan POLL beserta polling-nya dan memastikan user peserta chat
+func (ctrl *WSController) pollMessage(ctx context.Context, userID, messageID string) (*db.MessageModel, *db.PollModel, error) {
+	msg, err := ctrl.ChatRepo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ctrl.ChatRepo.IsParticipant(ctx, msg.ChatID, userID) {
+		return nil, nil, repositories.ErrNotParticipant
+	}
+	poll, ok := msg.Poll()
+	if !ok {
+		return nil, nil, repositories.ErrNotPoll
+	}
+	return msg, poll, nil
+}
+
+// refreshPoll mengambil ulang hasil polling lalu mengirim event "poll_updated" ke peserta yang online.
+// Payload dibuat per penerima karena flag votedByMe berbeda untuk tiap user.
+func (ctrl *WSController) refreshPoll(ctx context.Context, msg *db.MessageModel, userID string) (*models.PollDTO, error) {
+	pollID, _ := msg.PollID()
+	poll, err := ctrl.ChatRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(msg.ChatID),
+	).Exec(ctx)
+
+	ctrl.mu.Lock()
+	for _, p := range participants {
+		if len(ctrl.Clients[p.UserID]) == 0 {
+			continue
+		}
+		payload, _ := json.Marshal(WSMessage{
+			Type:   "poll_updated",
+			ChatID: msg.ChatID,
+			Data: gin.H{
+				"messageId": msg.ID,
+				"poll":      toPollDTO(poll, p.UserID),
+			},
+		})
+		ctrl.sendToUser(p.UserID, payload)
+	}
+	ctrl.mu.Unlock()
+
+	return toPollDTO(poll, userID), nil
+}
+
+// PostInfoMessage menyimpan pesan sistem bertipe INFO (mis. "Alice menyematkan pesan")
+// lalu menyebarkannya seperti pesan biasa. Tidak melewati validasi kirim milik SendMessage.
+func (ctrl *WSController) PostInfoMessage(ctx context.Context, actorID, chatID, content string) (*db.MessageModel, error) {
+	infoMsg, err := ctrl.ChatRepo.CreateMessage(ctx, actorID, chatID, content, db.MessageTypeInfo)
+	if err != nil {
+		return nil, err
+	}
+	ctrl.BroadcastMessage(actorID, infoMsg)
+	return infoMsg, nil
+}
+
+// BroadcastEvent mengirim satu event yang sama ke semua peserta chat yang online
+func (ctrl *WSController) BroadcastEvent(chatID string, event WSMessage) {
+	ctrl.broadcastEventExcept(chatID, "", event)
+}
+
+// broadcastEventExcept seperti BroadcastEvent, tetapi melewati satu user (mis. pelaku event)
+func (ctrl *WSController) broadcastEventExcept(chatID, excludeUserID string, event WSMessage) {
+	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatID),
+	).Exec(context.Background())
+
+	event.ChatID = chatID
+	payload, _ := json.Marshal(event)
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for _, p := range participants {
+		if p.UserID == excludeUserID {
+			continue
+		}
+		ctrl.sendToUser(p.UserID, payload)
+	}
+}
+
+// sendError memberi tahu pengirim bahwa aksinya via WebSocket ditolak
+func (ctrl *WSController) sendError(userID, chatID string, err error) {
+	payload, _ := json.Marshal(WSMessage{
+		Type:    "error",
+		ChatID:  chatID,
+		Content: utils.SafeErrorMessage(err),
+	})
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.sendToUser(userID, payload)
+}
+
+// handleReadReceipt memproses "mark_read" (baca s/d pesan tertentu).
+// "read_receipt" lama diperlakukan sama: membaca satu pesan berarti membaca semua sebelumnya.
+func (ctrl *WSController) handleReadReceipt(userID string, msg WSMessage) {
+	messageID, ok := msg.Data.(string)
+	if !ok || messageID == "" {
+		log.Println("[WS] ID pesan tidak valid dalam", msg.Type)
+		return
+	}
+
+	chatID := msg.ChatID
+	if chatID == "" {
+		target, err := ctrl.ChatRepo.GetMessageByID(context.Background(), messageID)
+		if err != nil {
+			ctrl.sendError(userID, "", err)
+			return
+		}
+		chatID = target.ChatID
+	}
+
+	if err := ctrl.MarkRead(context.Background(), userID, chatID, messageID); err != nil {
+		log.Println("[WS] Gagal memproses mark_read:", err)
+		ctrl.sendError(userID, chatID, err)
+	}
+}
+
+// MarkRead memajukan cursor baca user, memberi tahu anggota chat posisi baca terbaru,
+// dan mengirim "status_update" ke pengirim pesan yang kini sudah dibaca semua penerima (Centang Biru).
+func (ctrl *WSController) MarkRead(ctx context.Context, userID, chatID, messageID string) error {
+	participant, readMsgs, err := ctrl.ChatRepo.MarkChatRead(ctx, chatID, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	if lastReadID, ok := participant.LastReadMessageID(); ok {
+		lastReadAt, _ := participant.LastReadAt()
+		ctrl.BroadcastEvent(chatID, WSMessage{
+			Type:   "read_updated",
+			ChatID: chatID,
+			Data: gin.H{
+				"userId":            userID,
+				"lastReadMessageId": lastReadID,
+				"lastReadAt":        lastReadAt,
+			},
+		})
+	}
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for _, m := range readMsgs {
+		payload, _ := json.Marshal(WSMessage{
+			Type:    "status_update",
+			ChatID:  chatID,
+			Content: "Pesan telah dibaca",
+			Data:    m,
+		})
+		ctrl.sendToUser(m.SenderID, payload)
+	}
+	return nil
+}
+
+// NotifyStatusUpdate memberitahu semua user yang online tentang status baru (Global Notification).
+func (ctrl *WSController) NotifyStatusUpdate(ownerID, ownerName string, statusData interface{}) {
+	payload, _ := json.Marshal(WSMessage{
+		Type:    "status_update",
+		Content: ownerName + " baru saja mengunggah status baru!",
+		Data:    statusData,
+	})
+
+	// User yang diblokir pemilik status tidak ikut diberi tahu
+	blocked := make(map[string]bool)
+	if blocks, err := ctrl.UserRepo.GetBlockedUsers(context.Background(), ownerID); err == nil {
+		for _, b := range blocks {
+			blocked[b.BlockedID] = true
+		}
+	}
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for userID := range ctrl.Clients {
+		if blocked[userID] {
+			continue
+		}
+		ctrl.sendToUser(userID, payload)
+	}
+}
+
+// NotifyUser mengirimkan notifikasi spesifik ke satu user (User-to-User Notification).
+func (ctrl *WSController) NotifyUser(targetUserID string, subType string, message string, data interface{}) {
+	payload, _ := json.Marshal(WSMessage{
+		Type:    "notification",
+		Content: message,
+		Data:    data,
+	})
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.sendToUser(targetUserID, payload)
+}
+
+// NotifyGroup memberitahu semua anggota grup kecuali pengirim (Group Event Notification).
+func (ctrl *WSController) NotifyGroup(chatID string, excludeUserID string, message string, data interface{}) {
+	// Ambil daftar peserta chat dari DB
+	participants, _ := ctrl.ChatRepo.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatID),
+	).Exec(context.Background())
+
+	payload, _ := json.Marshal(WSMessage{
+		Type:    "notification",
+		Content: message,
+		Data:    data,
+	})
+
+	now := time.Now()
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for _, p := range participants {
+		// Jangan kirim ke diri sendiri jika sedang aktif di thread ini
+		if p.UserID == excludeUserID {
+			continue
+		}
+		// Chat yang di-mute tidak memunculkan notifikasi
+		if repositories.IsMuted(p, now) {
+			continue
+		}
+		ctrl.sendToUser(p.UserID, payload)
+	}
+}
+
+// typingThrottle jeda minimum antar relay typing_start dari user yang sama di satu chat
+const typingThrottle = 3 * time.Second
+
+// typingTimeout batas waktu typing dianggap berhenti jika tidak ada typing_start baru
+const typingTimeout = 8 * time.Second
+
+// typingState status mengetik satu user di satu chat
+type typingState struct {
+	userID    string
+	chatID    string
+	lastRelay time.Time
+	timer     *time.Timer
+}
+
+// typingKey kunci map status mengetik
+func typingKey(userID, chatID string) string {
+	return userID + ":" + chatID
+}
+
+// handleTyping memproses typing_start / typing_stop dari socket.
+// typing_start di-throttle, dan otomatis dianggap berhenti setelah typingTimeout.
+func (ctrl *WSController) handleTyping(userID string, msg WSMessage) {
+	if msg.ChatID == "" {
+		return
+	}
+	if msg.Type == "typing_stop" {
+		ctrl.stopTyping(userID, msg.ChatID)
+		return
+	}
+
+	key := typingKey(userID, msg.ChatID)
+	ctrl.typingMu.Lock()
+	if state, ok := ctrl.typing[key]; ok {
+		// Masih mengetik: perpanjang timeout, relay ulang hanya jika sudah lewat throttle
+		state.timer.Reset(typingTimeout)
+		if time.Since(state.lastRelay) < typingThrottle {
+			ctrl.typingMu.Unlock()
+			return
+		}
+		state.lastRelay = time.Now()
+		ctrl.typingMu.Unlock()
+		ctrl.relayTyping(userID, msg.ChatID, true)
+		return
+	}
+	ctrl.typingMu.Unlock()
+
+	// Keanggotaan cukup dicek saat mulai mengetik, bukan tiap keystroke
+	if !ctrl.ChatRepo.IsParticipant(context.Background(), msg.ChatID, userID) {
+		ctrl.sendError(userID, msg.ChatID, repositories.ErrNotParticipant)
+		return
+	}
+	// Status mengetik tidak diteruskan di chat direct yang salah satu pihaknya memblokir
+	if peerID, ok := ctrl.ChatRepo.GetDirectPeerID(context.Background(), msg.ChatID, userID); ok &&
+		ctrl.ChatRepo.IsBlockedEitherWay(context.Background(), userID, peerID) {
+		return
+	}
+
+	chatID := msg.ChatID
+	ctrl.typingMu.Lock()
+	if _, ok := ctrl.typing[key]; ok {
+		ctrl.typingMu.Unlock()
+		return
+	}
+	ctrl.typing[key] = &typingState{
+		userID:    userID,
+		chatID:    chatID,
+		lastRelay: time.Now(),
+		timer: time.AfterFunc(typingTimeout, func() {
+			ctrl.stopTyping(userID, chatID)
+		}),
+	}
+	ctrl.typingMu.Unlock()
+	ctrl.relayTyping(userID, chatID, true)
+}
+
+// stopTyping menghapus status mengetik dan memberi tahu peserta lain (jika memang sedang mengetik)
+func (ctrl *WSController) stopTyping(userID, chatID string) {
+	key := typingKey(userID, chatID)
+	ctrl.typingMu.Lock()
+	state, ok := ctrl.typing[key]
+	if ok {
+		state.timer.Stop()
+		delete(ctrl.typing, key)
+	}
+	ctrl.typingMu.Unlock()
+
+	if ok {
+		ctrl.relayTyping(userID, chatID, false)
+	}
+}
+
+// stopAllTyping menghentikan semua status mengetik user (dipanggil saat user offline)
+func (ctrl *WSController) stopAllTyping(userID string) {
+	var chatIDs []string
+	ctrl.typingMu.Lock()
+	for _, state := range ctrl.typing {
+		if state.userID == userID {
+			chatIDs = append(chatIDs, state.chatID)
+		}
+	}
+	ctrl.typingMu.Unlock()
+
+	for _, chatID := range chatIDs {
+		ctrl.stopTyping(userID, chatID)
+	}
+}
+
+// relayTyping meneruskan status mengetik ke peserta chat lain
+func (ctrl *WSController) relayTyping(userID, chatID string, typing bool) {
+	eventType := "typing_stop"
+	if typing {
+		eventType = "typing_start"
+	}
+	ctrl.broadcastEventExcept(chatID, userID, WSMessage{
+		Type: eventType,
+		Data: gin.H{"userId": userID},
+	})
+}
+
+// presenceSubscribeLimit batas jumlah user yang bisa di-subscribe dalam satu event
+const presenceSubscribeLimit = 200
+
+// handlePresenceSubscription memproses presence_subscribe / presence_unsubscribe.
+// Data berisi daftar ID user; hanya user yang tersimpan di kontak yang bisa di-subscribe.
+func (ctrl *WSController) handlePresenceSubscription(userID string, msg WSMessage) {
+	rawIDs, ok := msg.Data.([]interface{})
+	if !ok {
+		log.Println("[WS] Data presence tidak valid")
+		return
+	}
+	if len(rawIDs) > presenceSubscribeLimit {
+		rawIDs = rawIDs[:presenceSubscribeLimit]
+	}
+
+	for _, raw := range rawIDs {
+		targetID, ok := raw.(string)
+		if !ok || targetID == "" || targetID == userID {
+			continue
+		}
+
+		if msg.Type == "presence_unsubscribe" {
+			ctrl.mu.Lock()
+			delete(ctrl.presenceSubs[targetID], userID)
+			ctrl.mu.Unlock()
+			continue
+		}
+
+		if !ctrl.canSeePresence(context.Background(), userID, targetID) {
+			continue
+		}
+		presence, err := ctrl.GetPresence(context.Background(), userID, targetID)
+		if err != nil {
+			continue
+		}
+		payload, _ := json.Marshal(WSMessage{
+			Type: "presence",
+			Data: presence,
+		})
+
+		// Daftarkan subscriber lalu kirim status terkini sebagai snapshot awal
+		ctrl.mu.Lock()
+		subs, ok := ctrl.presenceSubs[targetID]
+		if !ok {
+			subs = make(map[string]bool)
+			ctrl.presenceSubs[targetID] = subs
+		}
+		subs[userID] = true
+		ctrl.sendToUser(userID, payload)
+		ctrl.mu.Unlock()
+	}
+}
+
+// canSeePresence aturan visibilitas presence yang sama untuk REST dan presence_subscribe:
+// viewer menyimpan user sebagai kontak dan tidak diblokir olehnya.
+func (ctrl *WSController) canSeePresence(ctx context.Context, viewerID, userID string) bool {
+	if viewerID == userID {
+		return true
+	}
+	return ctrl.ContactRepo.IsContact(viewerID, userID) && !ctrl.ChatRepo.IsBlocked(ctx, userID, viewerID)
+}
+
+// GetPresence mengambil status online / terakhir dilihat seorang user dari sudut pandang viewerID.
+// Jika viewer tidak boleh melihat presence user (lihat canSeePresence), status online & terakhir
+// dilihat disembunyikan tanpa membedakan apakah user tersebut ada.
+func (ctrl *WSController) GetPresence(ctx context.Context, viewerID, userID string) (*models.PresenceDTO, error) {
+	if !ctrl.canSeePresence(ctx, viewerID, userID) {
+		return &models.PresenceDTO{UserID: userID}, nil
+	}
+	user, err := ctrl.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	presence := &models.PresenceDTO{
+		UserID: user.ID,
+		Online: ctrl.IsOnline(user.ID),
+	}
+	if v, ok := user.LastSeenAt(); ok && !presence.Online {
+		presence.LastSeenAt = &v
+	}
+	return presence, nil
+}
+
+// RevokePresence memutus langganan presence subscriberID terhadap targetID (mis. setelah diblokir)
+func (ctrl *WSController) RevokePresence(targetID, subscriberID string) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	delete(ctrl.presenceSubs[targetID], subscriberID)
+}
+
+// setPresence mencatat perubahan online/offline (lastSeenAt saat offline) dan memberi tahu subscriber
+func (ctrl *WSController) setPresence(userID string, online bool) {
+	// User sempat reconnect / putus lagi sebelum event ini diproses
+	if ctrl.IsOnline(userID) != online {
+		return
+	}
+
+	presence := models.PresenceDTO{UserID: userID, Online: online}
+	if !online {
+		now := time.Now()
+		if _, err := ctrl.UserRepo.UpdateLastSeen(context.Background(), userID, now); err != nil {
+			log.Println("[WS] Gagal menyimpan lastSeenAt:", err)
+		}
+		presence.LastSeenAt = &now
+	}
+
+	payload, _ := json.Marshal(WSMessage{
+		Type: "presence",
+		Data: presence,
+	})
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for subscriberID := range ctrl.presenceSubs[userID] {
+		ctrl.sendToUser(subscriberID, payload)
+	}
+
+	// User offline tidak perlu lagi menerima update presence orang lain
+	if !online {
+		for targetID, subs := range ctrl.presenceSubs {
+			delete(subs, userID)
+			if len(subs) == 0 {
+				delete(ctrl.presenceSubs, targetID)
+			}
+		}
+	}
+}
+
+// sweepBatchSize jumlah maksimal pesan kedaluwarsa yang dihapus per putaran sweeper
+const sweepBatchSize = 500
+
+// RunMessageSweeper berjalan di background dan menghapus permanen pesan sementara yang kedaluwarsa.
+// Interval dibaca dari MESSAGE_SWEEP_INTERVAL_SECONDS (default 60 detik).
+func (ctrl *WSController) RunMessageSweeper() {
+	seconds, _ := strconv.Atoi(os.Getenv("MESSAGE_SWEEP_INTERVAL_SECONDS"))
+	if seconds <= 0 {
+		seconds = 60
+	}
+
+	ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctrl.sweepExpiredMessages()
+	}
+}
+
+// sweepExpiredMessages menghapus pesan kedaluwarsa beserta medianya,
+// lalu mengirim event "messages_expired" agar client ikut membersihkan cache lokal.
+func (ctrl *WSController) sweepExpiredMessages() {
+	ctx := context.Background()
+	for {
+		expired, err := ctrl.ChatRepo.DeleteExpiredMessages(ctx, time.Now(), sweepBatchSize)
+		if err != nil {
+			log.Println("[Sweeper] Gagal menghapus pesan kedaluwarsa:", err)
+			return
+		}
+		if len(expired) == 0 {
+			return
+		}
+
+		byChat := make(map[string][]string)
+		for _, m := range expired {
+			byChat[m.ChatID] = append(byChat[m.ChatID], m.ID)
+
+			// Hanya file yang tercatat diunggah untuk pesan ini yang dihapus, dan hanya jika
+			// tidak dipakai di tempat lain (mis. salinan hasil forward)
+			if upload, ok := m.MediaUpload(); ok && !ctrl.MediaRepo.IsReferenced(ctx, upload.URL) {
+				if err := config.DeleteFile(ctx, upload.PublicID, upload.ResourceType); err != nil {
+					log.Println("[Sweeper] Gagal menghapus media:", err)
+					continue
+				}
+				if err := ctrl.MediaRepo.Delete(ctx, upload.ID); err != nil {
+					log.Println("[Sweeper] Gagal menghapus catatan media:", err)
+				}
+			}
+		}
+
+		for chatID, ids := range byChat {
+			ctrl.BroadcastEvent(chatID, WSMessage{
+				Type: "messages_expired",
+				Data: gin.H{"messageIds": ids},
+			})
+		}
+
+		if len(expired) < sweepBatchSize {
+			return
+		}
+	}
+}
+
+// liveLocationMinInterval jeda minimum antar titik lokasi terkini dari user yang sama di satu chat.
+// Titik yang datang lebih cepat diabaikan.
+const liveLocationMinInterval = 5 * time.Second
+
+// StartLiveLocation mengirim pesan LOCATION berisi titik awal lalu membuka sesi lokasi terkini
+// selama durasi yang dipilih. Sesi lama user di chat yang sama dihentikan lebih dulu.
+func (ctrl *WSController) StartLiveLocation(ctx context.Context, senderID, chatID string, input models.StartLiveLocationDTO) (*db.MessageModel, *db.LiveLocationModel, error) {
+	location := input.LocationPayload
+	msg, err := ctrl.SendMessage(ctx, senderID, chatID, models.SendMessageDTO{
+		Type:     string(db.MessageTypeLocation),
+		Location: &location,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	ended, err := ctrl.ChatRepo.EndActiveLiveLocations(ctx, chatID, senderID, now)
+	if err != nil {
+		log.Println("[LiveLocation] Gagal menghentikan sesi lama:", err)
+	}
+	for _, s := range ended {
+		ctrl.relayLiveLocationEnded(s, now)
+	}
+
+	expiresAt := msg.Timestamp.Add(time.Duration(input.DurationMinutes) * time.Minute)
+	session, err := ctrl.ChatRepo.CreateLiveLocation(ctx, msg.ID, chatID, senderID, *location.Latitude, *location.Longitude, expiresAt)
+	if err != nil {
+		// Pesan lokasi yang sudah tersebar ditarik lagi agar tidak tertinggal tanpa sesi
+		if deleted, delErr := ctrl.ChatRepo.DeleteMessageForEveryone(ctx, msg.ID); delErr != nil {
+			log.Println("[LiveLocation] Gagal menarik pesan tanpa sesi:", delErr)
+		} else {
+			ctrl.BroadcastEvent(chatID, WSMessage{
+				Type:   "message_deleted",
+				ChatID: chatID,
+				Data:   gin.H{"messageId": deleted.ID},
+			})
+		}
+		return nil, nil, err
+	}
+
+	ctrl.relayLiveLocation(msg, WSMessage{
+		Type: "live_location_started",
+		Data: toLiveLocationDTO(*session, now),
+	})
+	return msg, session, nil
+}
+
+// handleLiveLocation memproses live_location_update / live_location_stop dari socket.
+// Update di-throttle per user+chat sesuai liveLocationMinInterval.
+func (ctrl *WSController) handleLiveLocation(userID string, msg WSMessage) {
+	if msg.ChatID == "" {
+		return
+	}
+	if msg.Type == "live_location_stop" {
+		if _, err := ctrl.StopLiveLocation(context.Background(), userID, msg.ChatID); err != nil {
+			ctrl.sendError(userID, msg.ChatID, err)
+		}
+		return
+	}
+
+	var point models.LiveLocationPointDTO
+	raw, _ := json.Marshal(msg.Data)
+	if err := json.Unmarshal(raw, &point); err != nil || !validLiveLocationPoint(point) {
+		log.Println("[WS] Titik lokasi terkini tidak valid")
+		return
+	}
+
+	// Cek awal tanpa mencatat waktu, supaya spam tidak sampai ke database; waktu titik baru
+	// dicatat UpdateLiveLocation setelah sesi terbukti aktif
+	ctrl.livePointsMu.Lock()
+	last, ok := ctrl.livePoints[typingKey(userID, msg.ChatID)]
+	ctrl.livePointsMu.Unlock()
+	if ok && time.Since(last) < liveLocationMinInterval {
+		return
+	}
+
+	if _, err := ctrl.UpdateLiveLocation(context.Background(), userID, msg.ChatID, point); err != nil {
+		log.Println("[WS] Gagal memperbarui lokasi terkini:", err)
+		ctrl.sendError(userID, msg.ChatID, err)
+	}
+}
+
+// allowLivePoint mencatat waktu titik lokasi terkini user di chat.
+// false jika titik sebelumnya belum lewat liveLocationMinInterval.
+func (ctrl *WSController) allowLivePoint(userID, chatID string, now time.Time) bool {
+	key := typingKey(userID, chatID)
+	ctrl.livePointsMu.Lock()
+	defer ctrl.livePointsMu.Unlock()
+	if last, ok := ctrl.livePoints[key]; ok && now.Sub(last) < liveLocationMinInterval {
+		return false
+	}
+	ctrl.livePoints[key] = now
+	return true
+}
+
+// validLiveLocationPoint memvalidasi koordinat (wajib) serta akurasi & arah (opsional)
+func validLiveLocationPoint(p models.LiveLocationPointDTO) bool {
+	if p.Latitude == nil || p.Longitude == nil {
+		return false
+	}
+	if *p.Latitude < -90 || *p.Latitude > 90 || *p.Longitude < -180 || *p.Longitude > 180 {
+		return false
+	}
+	if p.Accuracy != nil && *p.Accuracy < 0 {
+		return false
+	}
+	if p.Heading != nil && (*p.Heading < 0 || *p.Heading > 360) {
+		return false
+	}
+	return true
+}
+
+// UpdateLiveLocation menyimpan titik terbaru sesi aktif user lalu meneruskannya ke peserta chat.
+// Sesi dihentikan jika user sudah bukan peserta atau salah satu pihak chat direct memblokir.
+// Titik yang datang sebelum liveLocationMinInterval diabaikan (mengembalikan sesi tanpa perubahan).
+func (ctrl *WSController) UpdateLiveLocation(ctx context.Context, userID, chatID string, point models.LiveLocationPointDTO) (*db.LiveLocationModel, error) {
+	now := time.Now()
+	session, err := ctrl.ChatRepo.GetActiveLiveLocation(ctx, chatID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ctrl.ChatRepo.IsParticipant(ctx, chatID, userID) {
+		ctrl.endLiveLocation(ctx, session, now)
+		return nil, repositories.ErrNotParticipant
+	}
+	if peerID, ok := ctrl.ChatRepo.GetDirectPeerID(ctx, chatID, userID); ok &&
+		ctrl.ChatRepo.IsBlockedEitherWay(ctx, userID, peerID) {
+		ctrl.endLiveLocation(ctx, session, now)
+		return nil, repositories.ErrUserBlocked
+	}
+	if !ctrl.allowLivePoint(userID, chatID, now) {
+		return session, nil
+	}
+
+	updated, err := ctrl.ChatRepo.UpdateLiveLocationPoint(ctx, session.ID, *point.Latitude, *point.Longitude, point.Accuracy, point.Heading, now)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl.relayLiveLocation(session.Message(), WSMessage{
+		Type: "live_location_updated",
+		Data: toLiveLocationDTO(*updated, now),
+	})
+	return updated, nil
+}
+
+// StopLiveLocation menghentikan sesi lokasi terkini user di chat atas permintaan pengirim
+func (ctrl *WSController) StopLiveLocation(ctx context.Context, userID, chatID string) (*db.LiveLocationModel, error) {
+	now := time.Now()
+	session, err := ctrl.ChatRepo.GetActiveLiveLocation(ctx, chatID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctrl.endLiveLocation(ctx, session, now); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// endLiveLocation menghentikan satu sesi lalu mengabarkan peserta chat
+func (ctrl *WSController) endLiveLocation(ctx context.Context, session *db.LiveLocationModel, now time.Time) error {
+	if _, err := ctrl.ChatRepo.EndLiveLocation(ctx, session.ID, now); err != nil {
+		log.Println("[LiveLocation] Gagal menghentikan sesi:", err)
+		return err
+	}
+	ctrl.relayLiveLocationEnded(*session, now)
+	return nil
+}
+
+// relayLiveLocationEnded mengirim event "live_location_ended" dan membersihkan state rate limit sesi.
+// session wajib memuat relasi Message.
+func (ctrl *WSController) relayLiveLocationEnded(session db.LiveLocationModel, endedAt time.Time) {
+	ctrl.livePointsMu.Lock()
+	delete(ctrl.livePoints, typingKey(session.UserID, session.ChatID))
+	ctrl.livePointsMu.Unlock()
+
+	dto := toLiveLocationDTO(session, endedAt)
+	dto.EndedAt = &endedAt
+	dto.IsActive = false
+	ctrl.relayLiveLocation(session.Message(), WSMessage{
+		Type: "live_location_ended",
+		Data: dto,
+	})
+}
+
+// relayLiveLocation mengirim event lokasi terkini ke peserta chat.
+// Sesi dari pesan yang di-drop karena blokir hanya dikabarkan ke pengirimnya.
+func (ctrl *WSController) relayLiveLocation(msg *db.MessageModel, event WSMessage) {
+	if !msg.DroppedByBlock {
+		ctrl.BroadcastEvent(msg.ChatID, event)
+		return
+	}
+
+	event.ChatID = msg.ChatID
+	payload, _ := json.Marshal(event)
+	ctrl.mu.Lock()
+	ctrl.sendToUser(msg.SenderID, payload)
+	ctrl.mu.Unlock()
+}
+
+// RunLiveLocationSweeper berjalan di background dan menghentikan sesi lokasi terkini yang kedaluwarsa.
+// Interval dibaca dari LIVE_LOCATION_SWEEP_INTERVAL_SECONDS (default 30 detik).
+func (ctrl *WSController) RunLiveLocationSweeper() {
+	seconds, _ := strconv.Atoi(os.Getenv("LIVE_LOCATION_SWEEP_INTERVAL_SECONDS"))
+	if seconds <= 0 {
+		seconds = 30
+	}
+
+	ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctrl.sweepExpiredLiveLocations()
+	}
+}
+
+// sweepExpiredLiveLocations menandai sesi kedaluwarsa sebagai selesai lalu mengirim "live_location_ended".
+// Update yang masuk setelah expiresAt sudah ditolak sebelum sweeper berjalan.
+func (ctrl *WSController) sweepExpiredLiveLocations() {
+	ctx := context.Background()
+	for {
+		now := time.Now()
+		expired, err := ctrl.ChatRepo.EndExpiredLiveLocations(ctx, now, sweepBatchSize)
+		if err != nil {
+			log.Println("[Sweeper] Gagal menghentikan lokasi terkini kedaluwarsa:", err)
+			return
+		}
+		for _, s := range expired {
+			ctrl.relayLiveLocationEnded(s, now)
+		}
+		if len(expired) < sweepBatchSize {
+			return
+		}
+	}
+}
+
+// linkPreviewConcurrency batas fetch preview link yang berjalan bersamaan
+const linkPreviewConcurrency = 4
+
+// linkPreviewJobTimeout batas waktu total satu proses preview (fetch + simpan)
+const linkPreviewJobTimeout = 15 * time.Second
+
+// newLinkPreviewFetcher membuat fetcher preview link dari konfigurasi env
+func newLinkPreviewFetcher() *utils.LinkPreviewFetcher {
+	seconds, _ := strconv.Atoi(os.Getenv("LINK_PREVIEW_TIMEOUT_SECONDS"))
+	if seconds <= 0 {
+		seconds = 5
+	}
+	maxKB, _ := strconv.Atoi(os.Getenv("LINK_PREVIEW_MAX_KB"))
+	if maxKB <= 0 {
+		maxKB = 512
+	}
+	allowPrivate := os.Getenv("LINK_PREVIEW_ALLOW_PRIVATE") == "true"
+	return utils.NewLinkPreviewFetcher(time.Duration(seconds)*time.Second, int64(maxKB)*1024, allowPrivate)
+}
+
+// linkPreviewCacheTTL lama cache preview per URL sebelum diambil ulang
+func linkPreviewCacheTTL() time.Duration {
+	hours, _ := strconv.Atoi(os.Getenv("LINK_PREVIEW_CACHE_HOURS"))
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// unfurlLink mengambil preview URL pertama di pesan (dari cache jika masih segar),
+// menautkannya ke pesan, lalu mengirim "message_updated" ke peserta chat.
+func (ctrl *WSController) unfurlLink(msg *db.MessageModel) {
+	link := utils.ExtractURL(msg.Content)
+	if link == "" {
+		return
+	}
+
+	ctrl.previewSem <- struct{}{}
+	defer func() { <-ctrl.previewSem }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewJobTimeout)
+	defer cancel()
+
+	preview, err := ctrl.ChatRepo.GetLinkPreview(ctx, link)
+	if err != nil || time.Since(preview.FetchedAt) > ctrl.previewTTL {
+		meta, fetchErr := ctrl.previews.Fetch(ctx, link)
+		if fetchErr != nil {
+			log.Println("[WS] Gagal mengambil preview link:", fetchErr)
+			meta = &utils.LinkPreview{}
+		}
+		preview, err = ctrl.ChatRepo.SaveLinkPreview(ctx, link, meta.Title, meta.Description, meta.ImageURL, meta.SiteName, fetchErr != nil)
+		if err != nil {
+			log.Println("[WS] Gagal menyimpan preview link:", err)
+			return
+		}
+	}
+	if preview.Failed {
+		return
+	}
+
+	if err := ctrl.ChatRepo.AttachLinkPreview(ctx, msg.ID, preview.ID); err != nil {
+		log.Println("[WS] Gagal menautkan preview link:", err)
+		return
+	}
+	updated, err := ctrl.ChatRepo.GetMessageByID(ctx, msg.ID)
+	if err != nil {
+		return
+	}
+	event := WSMessage{
+		Type: "message_updated",
+		Data: toMessageDTO(*updated),
+	}
+	if updated.DroppedByBlock {
+		event.ChatID = msg.ChatID
+		payload, _ := json.Marshal(event)
+		ctrl.mu.Lock()
+		ctrl.sendToUser(updated.SenderID, payload)
+		ctrl.mu.Unlock()
+		return
 	}
+	ctrl.BroadcastEvent(msg.ChatID, event)
 }
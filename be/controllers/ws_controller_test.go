@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"chat-app-be/prisma/db"
+	"chat-app-be/repositories"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSendToUserReportsDroppedFrames(t *testing.T) {
+	// Channel tanpa buffer & tanpa pembaca = buffer socket penuh
+	full := &Client{UserID: "bob", Send: make(chan []byte)}
+	ctrl := &WSController{Clients: map[string]map[*Client]bool{
+		"bob": {full: true},
+	}}
+
+	if ctrl.sendToUser("bob", []byte("hai")) {
+		t.Fatal("sendToUser() = true padahal frame dibuang karena buffer penuh")
+	}
+	if ctrl.sendToUser("carol", []byte("hai")) {
+		t.Fatal("sendToUser() = true untuk user yang tidak online")
+	}
+
+	// Cukup satu perangkat yang menerima agar pesan dianggap sampai
+	open := &Client{UserID: "bob", Send: make(chan []byte, 1)}
+	ctrl.Clients["bob"][open] = true
+	if !ctrl.sendToUser("bob", []byte("hai")) {
+		t.Fatal("sendToUser() = false padahal satu socket menerima frame")
+	}
+	if got := len(open.Send); got != 1 {
+		t.Fatalf("socket terbuka menerima %d frame, want 1", got)
+	}
+}
+
+func TestDirectBlockPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		senderBlocksPeer bool
+		peerBlocksSender bool
+		wantDropped      bool
+		wantErr          error
+	}{
+		{name: "tanpa blokir", wantDropped: false},
+		{name: "pengirim diblokir lawan bicara", peerBlocksSender: true, wantDropped: true},
+		{name: "pengirim memblokir lawan bicara", senderBlocksPeer: true, wantErr: repositories.ErrBlockingUser},
+		{name: "saling blokir", senderBlocksPeer: true, peerBlocksSender: true, wantErr: repositories.ErrBlockingUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped, err := directBlockPolicy(tt.senderBlocksPeer, tt.peerBlocksSender)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped = %v, want %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestBroadcastDroppedMessageOnlyReachesSender(t *testing.T) {
+	alice := &Client{UserID: "alice", Send: make(chan []byte, 1)}
+	bob := &Client{UserID: "bob", Send: make(chan []byte, 1)}
+	ctrl := &WSController{Clients: map[string]map[*Client]bool{
+		"alice": {alice: true},
+		"bob":   {bob: true},
+	}}
+
+	msg := &db.MessageModel{InnerMessage: db.InnerMessage{
+		ID:             "m1",
+		ChatID:         "c1",
+		SenderID:       "alice",
+		Content:        "halo",
+		Type:           db.MessageTypeText,
+		DroppedByBlock: true,
+	}}
+	ctrl.BroadcastMessage("alice", msg)
+
+	if got := len(alice.Send); got != 1 {
+		t.Fatalf("pengirim menerima %d frame, want 1 (pesan harus tampak terkirim)", got)
+	}
+	if got := len(bob.Send); got != 0 {
+		t.Fatalf("user yang memblokir menerima %d frame, want 0", got)
+	}
+}
+
+func TestAllowLivePoint(t *testing.T) {
+	ctrl := &WSController{livePoints: make(map[string]time.Time)}
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	if !ctrl.allowLivePoint("alice", "c1", start) {
+		t.Fatal("titik pertama ditolak")
+	}
+	if ctrl.allowLivePoint("alice", "c1", start.Add(liveLocationMinInterval/2)) {
+		t.Fatal("titik sebelum interval minimum diterima")
+	}
+	// Throttle berlaku per user+chat
+	if !ctrl.allowLivePoint("alice", "c2", start.Add(time.Second)) {
+		t.Fatal("titik di chat lain ikut di-throttle")
+	}
+	if !ctrl.allowLivePoint("alice", "c1", start.Add(liveLocationMinInterval)) {
+		t.Fatal("titik setelah interval minimum ditolak")
+	}
+}
+
+func TestMessageEvent(t *testing.T) {
+	top := db.MessageModel{InnerMessage: db.InnerMessage{ID: "m1"}}
+	if got := messageEvent(top); got != "chat" {
+		t.Errorf("messageEvent(pesan biasa) = %q, want %q", got, "chat")
+	}
+
+	rootID := "m1"
+	reply := db.MessageModel{InnerMessage: db.InnerMessage{ID: "m2", ThreadRootID: &rootID}}
+	if got := messageEvent(reply); got != "thread_reply" {
+		t.Errorf("messageEvent(balasan thread) = %q, want %q", got, "thread_reply")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"chat-app-be/middleware"
 	"chat-app-be/repositories"
 	"chat-app-be/routes"
+	"context"
 	"log"
 	"os"
 
@@ -41,15 +42,30 @@ func main() {
 	chatRepo := repositories.NewChatRepository(config.PkgClient)
 	statusRepo := repositories.NewStatusRepository(config.PkgClient)
 	searchRepo := repositories.NewSearchRepository(config.PkgClient)
+	if err := searchRepo.EnsureSearchIndex(context.Background()); err != nil {
+		log.Printf("Peringatan: gagal membuat index full-text pesan: %v", err)
+	}
 	contactRepo := repositories.NewContactRepository(config.PkgClient)
+	inviteRepo := repositories.NewInviteRepository(config.PkgClient)
+	scheduledRepo := repositories.NewScheduledMessageRepository(config.PkgClient)
+	broadcastRepo := repositories.NewBroadcastRepository(config.PkgClient)
+	reportRepo := repositories.NewReportRepository(config.PkgClient)
+	mediaRepo := repositories.NewMediaRepository(config.PkgClient)
 
 	// 5. Controllers
-	wsCtrl := controllers.NewWSController(chatRepo)
+	wsCtrl := controllers.NewWSController(chatRepo, userRepo, contactRepo, mediaRepo)
 	authCtrl := controllers.NewAuthController(userRepo)
 	chatCtrl := controllers.NewChatController(chatRepo, contactRepo, wsCtrl)
+	messageCtrl := controllers.NewMessageController(chatRepo, contactRepo, wsCtrl)
+	groupCtrl := controllers.NewGroupController(chatRepo, wsCtrl)
+	inviteCtrl := controllers.NewInviteController(chatRepo, inviteRepo, wsCtrl)
+	userCtrl := controllers.NewUserController(userRepo, wsCtrl)
+	scheduledCtrl := controllers.NewScheduledMessageController(scheduledRepo, chatRepo, wsCtrl)
+	broadcastCtrl := controllers.NewBroadcastController(broadcastRepo, chatRepo, contactRepo, wsCtrl)
 	statusCtrl := controllers.NewStatusController(statusRepo, chatRepo, wsCtrl)
-	mediaCtrl := controllers.NewMediaController()
-	searchCtrl := controllers.NewSearchController(searchRepo)
+	moderationCtrl := controllers.NewModerationController(reportRepo, chatRepo, statusRepo, userRepo, wsCtrl)
+	mediaCtrl := controllers.NewMediaController(mediaRepo)
+	searchCtrl := controllers.NewSearchController(searchRepo, chatRepo)
 
 	// 6. API Routes
 	api := r.Group("/api")
@@ -60,13 +76,22 @@ func main() {
 		// Protected Routes (Butuh Token)
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.ActiveAccount(userRepo)) // Tolak akun yang ditangguhkan moderator
 		{
 			routes.ChatRoutes(protected, chatCtrl)
+			routes.MessageRoutes(protected, messageCtrl)
+			routes.GroupRoutes(protected, groupCtrl)
+			routes.InviteRoutes(protected, inviteCtrl)
+			routes.UserRoutes(protected, userCtrl)
+			routes.ScheduledMessageRoutes(protected, scheduledCtrl)
+			routes.BroadcastRoutes(protected, broadcastCtrl)
 			routes.StatusRoutes(protected, statusCtrl)
+			routes.ModerationRoutes(protected, moderationCtrl)
 			routes.MediaRoutes(protected, mediaCtrl)
 			
 			// Global Search Route
 			protected.GET("/search", searchCtrl.HandleSearch)
+			protected.GET("/chats/:id/search", searchCtrl.SearchChat)
 			
 			// Contact Routes (New)
 			routes.RegisterContactRoutes(protected, contactRepo)
@@ -76,6 +101,13 @@ func main() {
 	// WebSocket Endpoint (Real-time)
 	r.GET("/ws", wsCtrl.HandleWS)
 
+	// Background job: hapus pesan sementara yang kedaluwarsa
+	go wsCtrl.RunMessageSweeper()
+	// Background job: hentikan sesi lokasi terkini yang kedaluwarsa
+	go wsCtrl.RunLiveLocationSweeper()
+	// Background job: kirim pesan terjadwal yang sudah jatuh tempo
+	go scheduledCtrl.RunScheduler()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "9000"
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"chat-app-be/repositories"
 	"fmt"
 	"net/http"
 	"os"
@@ -96,6 +97,40 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ActiveAccount memastikan akun pemilik token masih ada dan tidak sedang ditangguhkan.
+// Dipasang setelah AuthMiddleware; sekaligus menyimpan flag isModerator ke context.
+func ActiveAccount(userRepo *repositories.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userRepo.FindByID(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Akun tidak ditemukan"})
+			return
+		}
+
+		if until, suspended := repositories.ActiveSuspension(user); suspended {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":          "Akun Anda ditangguhkan sampai " + until.Format(time.RFC3339),
+				"suspendedUntil": until,
+			})
+			return
+		}
+
+		c.Set("isModerator", user.IsModerator)
+		c.Next()
+	}
+}
+
+// ModeratorOnly membatasi route untuk moderator. Dipasang setelah ActiveAccount.
+func ModeratorOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("isModerator") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: Khusus moderator"})
+			return
+		}
+		c.Next()
+	}
+}
+
 // CORSMiddleware mengatur izin akses dari frontend (Cross-Origin Resource Sharing)
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
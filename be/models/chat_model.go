@@ -4,15 +4,251 @@ import "time"
 
 // MessageDTO merepresentasikan struktur satu pesan chat
 type MessageDTO struct {
-	ID        string    `json:"id"`
-	SenderID  string    `json:"senderId"`
-	ChatID    string    `json:"chatId"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
-	Type      string    `json:"type"`      // TEXT, IMAGE, VIDEO, DOCUMENT, INFO
-	Status    string    `json:"status"`    // SENDING, SENT, DELIVERED, READ
-	IsDeleted bool      `json:"isDeleted"`
-	ReplyToID string    `json:"replyToId,omitempty"`
+	ID        string           `json:"id"`
+	SenderID  string           `json:"senderId"`
+	ChatID    string           `json:"chatId"`
+	Content   string           `json:"content"`
+	Timestamp time.Time        `json:"timestamp"`
+	EditedAt  *time.Time       `json:"editedAt,omitempty"`
+	Type      string           `json:"type"`      // TEXT, IMAGE, VIDEO, DOCUMENT, INFO, POLL, LOCATION, CONTACT, AUDIO
+	Status    string           `json:"status"`    // SENDING, SENT, DELIVERED, READ
+	IsDeleted bool             `json:"isDeleted"`
+	ReplyToID string           `json:"replyToId,omitempty"`
+	ReplyTo   *ReplyPreviewDTO `json:"replyTo,omitempty"` // Preview pesan yang dikutip
+	Reactions []ReactionDTO    `json:"reactions,omitempty"`
+
+	IsForwarded         bool `json:"isForwarded"`
+	ForwardCount        int  `json:"forwardCount"`
+	FrequentlyForwarded bool `json:"frequentlyForwarded"` // True jika sudah diteruskan berkali-kali
+
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Pesan sementara: waktu pesan akan dihapus
+
+	ThreadRootID string            `json:"threadRootId,omitempty"` // Diisi jika pesan adalah balasan di thread
+	Thread       *ThreadSummaryDTO `json:"thread,omitempty"`       // Ringkasan thread jika pesan ini root thread
+
+	Poll *PollDTO `json:"poll,omitempty"` // Diisi untuk pesan bertipe POLL
+
+	// Data terstruktur sesuai tipe pesan (hanya salah satu yang terisi)
+	Location *LocationPayload `json:"location,omitempty"`
+	Contact  *ContactPayload  `json:"contact,omitempty"`
+	Audio    *AudioPayload    `json:"audio,omitempty"`
+
+	LiveLocation *LiveLocationDTO `json:"liveLocation,omitempty"` // Diisi jika pesan LOCATION adalah sesi lokasi terkini
+
+	Mentions    []string `json:"mentions,omitempty"`    // ID user yang di-mention
+	MentionsAll bool     `json:"mentionsAll,omitempty"` // true jika pesan memuat @all
+
+	LinkPreview *LinkPreviewDTO `json:"linkPreview,omitempty"` // Diisi asinkron, dikabarkan lewat "message_updated"
+}
+
+// ChatSearchResultDTO adalah satu hasil pencarian pesan di dalam chat
+type ChatSearchResultDTO struct {
+	Message MessageDTO `json:"message"`
+	Snippet string     `json:"snippet"` // Potongan isi pesan (HTML-escaped) dengan kata yang cocok dalam <mark>
+	Rank    float64    `json:"rank"`
+}
+
+// LinkPreviewDTO adalah preview URL pertama di pesan (Open Graph / Twitter card)
+type LinkPreviewDTO struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageUrl    string `json:"imageUrl,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+}
+
+// ThreadSummaryDTO adalah ringkasan thread yang ditampilkan pada pesan root
+type ThreadSummaryDTO struct {
+	ReplyCount   int                    `json:"replyCount"`
+	LastReplyAt  *time.Time             `json:"lastReplyAt,omitempty"`
+	Participants []ThreadParticipantDTO `json:"participants"`
+	UnreadCount  int                    `json:"unreadCount"` // Balasan yang belum dibaca user yang meminta
+	IsFollowing  bool                   `json:"isFollowing"`
+}
+
+// ThreadParticipantDTO adalah anggota yang sudah membalas di thread
+type ThreadParticipantDTO struct {
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	AvatarUrl string `json:"avatarUrl"`
+}
+
+// ReactionDTO adalah agregat reaksi per emoji pada satu pesan
+type ReactionDTO struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reactedByMe"`
+}
+
+// ReactionInputDTO untuk request tambah/hapus reaksi
+type ReactionInputDTO struct {
+	Emoji string `json:"emoji" binding:"required,max=32"`
+}
+
+// ReplyPreviewDTO adalah ringkasan pesan yang dikutip, supaya client tidak perlu fetch ulang
+type ReplyPreviewDTO struct {
+	ID         string `json:"id"`
+	SenderID   string `json:"senderId"`
+	SenderName string `json:"senderName"`
+	Snippet    string `json:"snippet"` // Potongan isi pesan (kosong jika sudah dihapus)
+	Type       string `json:"type"`
+	IsDeleted  bool   `json:"isDeleted"`
+}
+
+// SendMessageDTO untuk request kirim pesan (REST) dan dipakai ulang oleh jalur WebSocket.
+// Content wajib untuk TEXT & media (berisi URL). Tipe LOCATION / CONTACT / AUDIO memakai
+// field terstruktur yang sesuai; content-nya diisi server sebagai teks fallback.
+type SendMessageDTO struct {
+	Content   string `json:"content"`
+	Type      string `json:"type" binding:"omitempty,oneof=TEXT IMAGE VIDEO DOCUMENT LOCATION CONTACT AUDIO"`
+	ReplyToID string `json:"replyToId"` // Opsional: ID pesan yang dibalas (harus di chat yang sama)
+	// Opsional: balas di thread milik pesan ini (tidak muncul di timeline utama)
+	ThreadRootID string `json:"threadRootId"`
+
+	Location *LocationPayload `json:"location"` // Wajib untuk tipe LOCATION
+	Contact  *ContactPayload  `json:"contact"`  // Wajib untuk tipe CONTACT
+	Audio    *AudioPayload    `json:"audio"`    // Wajib untuk tipe AUDIO
+}
+
+// LocationPayload adalah lokasi yang dibagikan (pesan bertipe LOCATION)
+type LocationPayload struct {
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+	Label     string   `json:"label,omitempty" binding:"max=100"`   // Nama tempat
+	Address   string   `json:"address,omitempty" binding:"max=300"` // Alamat lengkap
+}
+
+// StartLiveLocationDTO input untuk mulai berbagi lokasi terkini selama 15 menit, 1 jam, atau 8 jam
+type StartLiveLocationDTO struct {
+	LocationPayload
+	DurationMinutes int `json:"durationMinutes" binding:"required,oneof=15 60 480"`
+}
+
+// LiveLocationPointDTO titik lokasi terkini yang dikirim lewat WebSocket ("live_location_update")
+type LiveLocationPointDTO struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Accuracy  *float64 `json:"accuracy,omitempty"` // Meter
+	Heading   *float64 `json:"heading,omitempty"`  // Derajat 0-360
+}
+
+// LiveLocationDTO posisi terakhir satu sesi lokasi terkini
+type LiveLocationDTO struct {
+	MessageID string     `json:"messageId"`
+	ChatID    string     `json:"chatId"`
+	UserID    string     `json:"userId"`
+	UserName  string     `json:"userName,omitempty"`
+	Latitude  float64    `json:"latitude"`
+	Longitude float64    `json:"longitude"`
+	Accuracy  *float64   `json:"accuracy,omitempty"`
+	Heading   *float64   `json:"heading,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt"` // Waktu titik terakhir diterima
+	StartedAt time.Time  `json:"startedAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	IsActive  bool       `json:"isActive"`
+}
+
+// ContactPayload adalah kartu kontak (pesan bertipe CONTACT), mirip vCard.
+// UserID diisi jika kontak adalah user aplikasi; Name boleh kosong dan diambil dari profilnya.
+type ContactPayload struct {
+	UserID       string   `json:"userId,omitempty"`
+	Name         string   `json:"name" binding:"required_without=UserID,max=100"`
+	Phones       []string `json:"phones,omitempty" binding:"max=5,dive,min=3,max=32"`
+	Emails       []string `json:"emails,omitempty" binding:"max=5,dive,email"`
+	Organization string   `json:"organization,omitempty" binding:"max=100"`
+}
+
+// AudioPayload adalah pesan suara / file audio (pesan bertipe AUDIO)
+type AudioPayload struct {
+	URL        string `json:"url" binding:"required,url"`
+	DurationMs int    `json:"durationMs" binding:"required,min=1,max=3600000"`         // Maksimal 1 jam
+	Waveform   []int  `json:"waveform,omitempty" binding:"max=128,dive,min=0,max=255"` // Amplitudo untuk visualisasi
+	MimeType   string `json:"mimeType,omitempty" binding:"max=50"`
+}
+
+// ForwardMessageDTO untuk request meneruskan pesan ke satu atau lebih chat
+type ForwardMessageDTO struct {
+	ChatIDs []string `json:"chatIds" binding:"required,min=1,max=10,dive,required"`
+}
+
+// StarredMessageDTO adalah satu pesan favorit beserta nama pengirim & chat asalnya
+type StarredMessageDTO struct {
+	StarredAt  time.Time  `json:"starredAt"`
+	ChatID     string     `json:"chatId"`
+	ChatName   string     `json:"chatName"`
+	IsGroup    bool       `json:"isGroup"`
+	SenderName string     `json:"senderName"` // Alias kontak jika ada
+	Message    MessageDTO `json:"message"`
+}
+
+// PaginationDTO meta informasi halaman untuk response berhalaman
+type PaginationDTO struct {
+	Page    int  `json:"page"`
+	Limit   int  `json:"limit"`
+	HasMore bool `json:"hasMore"`
+}
+
+// AddMembersDTO untuk request menambah anggota grup
+type AddMembersDTO struct {
+	UserIDs []string `json:"userIds" binding:"required,min=1,dive,required"`
+}
+
+// UpdateGroupDTO untuk request ubah info grup. Field yang tidak dikirim tidak diubah.
+type UpdateGroupDTO struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Icon        *string `json:"icon" binding:"omitempty,url"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+}
+
+// MemberDTO merepresentasikan satu anggota grup beserta role-nya
+type MemberDTO struct {
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	AvatarUrl string    `json:"avatarUrl"`
+	Role      string    `json:"role"` // OWNER, ADMIN, MEMBER
+	JoinedAt  time.Time `json:"joinedAt"`
+}
+
+// ReadReceiptDTO adalah satu anggota yang sudah membaca pesan
+type ReadReceiptDTO struct {
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	AvatarUrl string    `json:"avatarUrl"`
+	ReadAt    time.Time `json:"readAt"` // Waktu cursor baca user terakhir dimajukan
+}
+
+// UpdateRoleDTO untuk request ubah role anggota (OWNER = alihkan kepemilikan)
+type UpdateRoleDTO struct {
+	Role string `json:"role" binding:"required,oneof=OWNER ADMIN MEMBER"`
+}
+
+// UpdatePermissionsDTO untuk request ubah matriks izin grup (ALL / ADMINS)
+type UpdatePermissionsDTO struct {
+	EditInfo   *string `json:"editInfo" binding:"omitempty,oneof=ALL ADMINS"`
+	AddMembers *string `json:"addMembers" binding:"omitempty,oneof=ALL ADMINS"`
+	Pin        *string `json:"pin" binding:"omitempty,oneof=ALL ADMINS"`
+	Send       *string `json:"send" binding:"omitempty,oneof=ALL ADMINS"` // ADMINS = mode pengumuman
+	// JoinApproval: join lewat tautan undangan harus disetujui admin
+	JoinApproval *bool `json:"joinApproval"`
+}
+
+// CreateInviteDTO untuk request membuat tautan undangan grup (semua field opsional)
+type CreateInviteDTO struct {
+	ExpiresInHours *int `json:"expiresInHours" binding:"omitempty,min=1,max=8760"`
+	MaxUses        *int `json:"maxUses" binding:"omitempty,min=1,max=10000"`
+}
+
+// InvitePreviewDTO adalah ringkasan grup yang ditampilkan sebelum user bergabung
+type InvitePreviewDTO struct {
+	ChatID           string   `json:"chatId"`
+	Name             string   `json:"name"`
+	Icon             string   `json:"icon,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	MemberCount      int      `json:"memberCount"`
+	MemberNames      []string `json:"memberNames"` // Beberapa nama anggota sebagai gambaran
+	RequiresApproval bool     `json:"requiresApproval"`
+	AlreadyMember    bool     `json:"alreadyMember"`
 }
 
 // ChatDTO merepresentasikan satu room chat
@@ -20,6 +256,188 @@ type ChatDTO struct {
 	ID          string      `json:"id"`
 	Name        string      `json:"name,omitempty"`
 	IsGroup     bool        `json:"isGroup"`
-	LastMessage interface{} `json:"lastMessage,omitempty"`
-	UnreadCount int         `json:"unreadCount"`
+	LastMessage interface{}       `json:"lastMessage,omitempty"`
+	UnreadCount int               `json:"unreadCount"`
+	UnreadMentionCount int        `json:"unreadMentionCount"` // Mention untuk user ini yang belum dibaca
+	LatestPin   *PinnedMessageDTO `json:"latestPin,omitempty"`
+
+	// Preferensi chat milik user yang meminta
+	IsMuted      bool       `json:"isMuted"`
+	MutedUntil   *time.Time `json:"mutedUntil,omitempty"` // Kosong jika mute selamanya / tidak di-mute
+	IsArchived   bool       `json:"isArchived"`
+	KeepArchived bool       `json:"keepArchived"`
+	IsPinned     bool       `json:"isPinned"`
+
+	DisappearingSeconds int `json:"disappearingSeconds"` // 0 = pesan sementara nonaktif
+
+	Draft *DraftDTO `json:"draft,omitempty"` // Draft pesan milik user yang meminta
+}
+
+// DraftDTO draft pesan user di satu chat (teks dan pesan yang akan dibalas)
+type DraftDTO struct {
+	Content   string    `json:"content"`
+	ReplyToID string    `json:"replyToId,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SaveDraftDTO input simpan draft. content kosong tanpa replyToId menghapus draft.
+type SaveDraftDTO struct {
+	Content   string `json:"content" binding:"max=4096"`
+	ReplyToID string `json:"replyToId"`
+}
+
+// DisappearingDTO untuk request ubah timer pesan sementara
+type DisappearingDTO struct {
+	Duration string `json:"duration" binding:"required,oneof=off 24h 7d 90d"`
+}
+
+// ChatPreferencesDTO untuk request ubah preferensi chat (field nil tidak diubah)
+type ChatPreferencesDTO struct {
+	Mute         *string `json:"mute" binding:"omitempty,oneof=8h 1w forever off"`
+	Archived     *bool   `json:"archived"`
+	KeepArchived *bool   `json:"keepArchived"`
+	Pinned       *bool   `json:"pinned"`
+}
+
+// PinnedMessageDTO merepresentasikan satu pesan yang disematkan di chat
+type PinnedMessageDTO struct {
+	ChatID       string      `json:"chatId"`
+	MessageID    string      `json:"messageId"`
+	PinnedByID   string      `json:"pinnedById"`
+	PinnedByName string      `json:"pinnedByName,omitempty"`
+	PinnedAt     time.Time   `json:"pinnedAt"`
+	Message      *MessageDTO `json:"message,omitempty"`
+}
+
+// PinMessageDTO untuk request menyematkan pesan
+type PinMessageDTO struct {
+	MessageID string `json:"messageId" binding:"required"`
+}
+
+// CreatePollDTO untuk request membuat polling di grup
+type CreatePollDTO struct {
+	Question      string     `json:"question" binding:"required,max=300"`
+	Options       []string   `json:"options" binding:"required,min=2,max=12,dive,required,max=100"`
+	AllowMultiple bool       `json:"allowMultiple"` // true = pilihan ganda
+	IsAnonymous   bool       `json:"isAnonymous"`   // true = daftar pemilih disembunyikan
+	ClosesAt      *time.Time `json:"closesAt"`      // Opsional: waktu poll ditutup otomatis
+}
+
+// VotePollDTO untuk request memberi suara. Daftar kosong berarti menarik suara.
+type VotePollDTO struct {
+	OptionIDs []string `json:"optionIds" binding:"max=12,dive,required"`
+}
+
+// PollDTO adalah hasil polling yang sudah diagregasi dari sudut pandang user yang meminta
+type PollDTO struct {
+	ID            string          `json:"id"`
+	Question      string          `json:"question"`
+	AllowMultiple bool            `json:"allowMultiple"`
+	IsAnonymous   bool            `json:"isAnonymous"`
+	ClosesAt      *time.Time      `json:"closesAt,omitempty"`
+	IsClosed      bool            `json:"isClosed"`
+	TotalVoters   int             `json:"totalVoters"` // Jumlah user unik yang memilih
+	Options       []PollOptionDTO `json:"options"`
+}
+
+// PollOptionDTO adalah satu opsi polling beserta jumlah suaranya
+type PollOptionDTO struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	VoteCount int    `json:"voteCount"`
+	VotedByMe bool   `json:"votedByMe"`
+}
+
+// PollVotersDTO adalah daftar pemilih per opsi (hanya untuk poll non-anonim)
+type PollVotersDTO struct {
+	OptionID string         `json:"optionId"`
+	Voters   []PollVoterDTO `json:"voters"`
+}
+
+// PollVoterDTO adalah satu pemilih pada opsi polling
+type PollVoterDTO struct {
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	AvatarUrl string    `json:"avatarUrl"`
+	VotedAt   time.Time `json:"votedAt"`
+}
+
+// CreateScheduledMessageDTO untuk request menjadwalkan pesan
+type CreateScheduledMessageDTO struct {
+	Content   string    `json:"content" binding:"required"`
+	Type      string    `json:"type" binding:"omitempty,oneof=TEXT IMAGE VIDEO DOCUMENT"`
+	ReplyToID string    `json:"replyToId"`
+	SendAt    time.Time `json:"sendAt" binding:"required"`
+}
+
+// UpdateScheduledMessageDTO untuk request ubah pesan terjadwal (field nil tidak diubah)
+type UpdateScheduledMessageDTO struct {
+	Content *string    `json:"content" binding:"omitempty,min=1"`
+	SendAt  *time.Time `json:"sendAt"`
+}
+
+// ScheduledMessageDTO merepresentasikan satu pesan terjadwal
+type ScheduledMessageDTO struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"chatId"`
+	Content   string    `json:"content"`
+	Type      string    `json:"type"`
+	ReplyToID string    `json:"replyToId,omitempty"`
+	SendAt    time.Time `json:"sendAt"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateBroadcastListDTO untuk request membuat daftar broadcast (penerima wajib dari kontak sendiri)
+type CreateBroadcastListDTO struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RecipientIDs []string `json:"recipientIds" binding:"required,min=1,max=256,dive,required"`
+}
+
+// UpdateBroadcastListDTO untuk request ubah daftar broadcast (field nil tidak diubah)
+type UpdateBroadcastListDTO struct {
+	Name         *string  `json:"name" binding:"omitempty,min=1,max=100"`
+	RecipientIDs []string `json:"recipientIds" binding:"omitempty,min=1,max=256,dive,required"`
+}
+
+// BroadcastListDTO merepresentasikan satu daftar broadcast beserta penerimanya
+type BroadcastListDTO struct {
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	Recipients []BroadcastRecipientDTO `json:"recipients"`
+	CreatedAt  time.Time               `json:"createdAt"`
+	UpdatedAt  time.Time               `json:"updatedAt"`
+}
+
+// BroadcastRecipientDTO adalah satu penerima daftar broadcast
+type BroadcastRecipientDTO struct {
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	AvatarUrl string `json:"avatarUrl"`
+	Color     string `json:"color"`
+}
+
+// BroadcastSendDTO untuk request kirim pesan ke daftar broadcast
+type BroadcastSendDTO struct {
+	Content string `json:"content" binding:"required"`
+	Type    string `json:"type" binding:"omitempty,oneof=TEXT IMAGE VIDEO DOCUMENT"`
+}
+
+// BroadcastDeliveryDTO adalah hasil pengiriman broadcast ke satu penerima.
+// Status: SENT, SKIPPED (penerima belum menyimpan kontak pengirim), atau FAILED.
+type BroadcastDeliveryDTO struct {
+	UserID    string `json:"userId"`
+	Status    string `json:"status"`
+	ChatID    string `json:"chatId,omitempty"`
+	MessageID string `json:"messageId,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// BroadcastResultDTO adalah ringkasan pengiriman broadcast
+type BroadcastResultDTO struct {
+	ListID  string                 `json:"listId"`
+	Sent    int                    `json:"sent"`
+	Skipped int                    `json:"skipped"`
+	Failed  int                    `json:"failed"`
+	Results []BroadcastDeliveryDTO `json:"results"`
 }
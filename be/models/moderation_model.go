@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateReportDTO input untuk melaporkan pesan, status, user, atau grup
+type CreateReportDTO struct {
+	TargetType string `json:"targetType" binding:"required,oneof=MESSAGE STATUS USER GROUP"`
+	TargetID   string `json:"targetId" binding:"required"`
+	Reason     string `json:"reason" binding:"required,oneof=SPAM HARASSMENT HATE_SPEECH VIOLENCE SEXUAL_CONTENT SCAM IMPERSONATION OTHER"`
+	Details    string `json:"details" binding:"max=1000"`
+}
+
+// ReportDTO ringkasan laporan yang dikembalikan ke pelapor
+type ReportDTO struct {
+	ID         string    `json:"id"`
+	TargetType string    `json:"targetType"`
+	TargetID   string    `json:"targetId"`
+	Reason     string    `json:"reason"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ReportSnapshotDTO salinan konten yang dilaporkan beserta konteksnya pada saat laporan dibuat.
+// Field Message / Status / User / Group diisi sesuai jenis target (laporan status ikut menyalin profil pemiliknya).
+type ReportSnapshotDTO struct {
+	CapturedAt time.Time            `json:"capturedAt"`
+	Message    *ReportedMessageDTO  `json:"message,omitempty"`
+	Status     *ReportedStatusDTO   `json:"status,omitempty"`
+	User       *ReportedUserDTO     `json:"user,omitempty"`
+	Group      *ReportedGroupDTO    `json:"group,omitempty"`
+	Context    []ReportedMessageDTO `json:"context"` // Pesan terakhir sebelum konten (terlama dulu)
+}
+
+// ReportedMessageDTO salinan satu pesan di snapshot laporan
+type ReportedMessageDTO struct {
+	ID         string          `json:"id"`
+	SenderID   string          `json:"senderId"`
+	SenderName string          `json:"senderName"`
+	Type       string          `json:"type"`
+	Content    string          `json:"content"`
+	IsDeleted  bool            `json:"isDeleted"`
+	Timestamp  time.Time       `json:"timestamp"`
+	EditedAt   *time.Time      `json:"editedAt,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"` // Data pesan LOCATION / CONTACT / AUDIO
+}
+
+// ReportedStatusDTO salinan status di snapshot laporan
+type ReportedStatusDTO struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Type      string    `json:"type"`
+	MediaUrl  string    `json:"mediaUrl,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Caption   string    `json:"caption,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ReportedUserDTO salinan profil user di snapshot laporan
+type ReportedUserDTO struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Username  string `json:"username,omitempty"`
+	AvatarUrl string `json:"avatarUrl,omitempty"`
+}
+
+// ReportedGroupDTO salinan info grup di snapshot laporan
+type ReportedGroupDTO struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Icon        string `json:"icon,omitempty"`
+	MemberCount int    `json:"memberCount"`
+}
+
+// ModerationUserDTO identitas singkat user di antrean moderasi
+type ModerationUserDTO struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// ModerationReportDTO laporan lengkap untuk moderator. Snapshot & Actions hanya diisi di detail laporan.
+type ModerationReportDTO struct {
+	ID               string                `json:"id"`
+	TargetType       string                `json:"targetType"`
+	TargetID         string                `json:"targetId"`
+	ChatID           string                `json:"chatId,omitempty"`
+	Reason           string                `json:"reason"`
+	Details          string                `json:"details,omitempty"`
+	Status           string                `json:"status"`
+	Reporter         *ModerationUserDTO    `json:"reporter,omitempty"`
+	ReportedUser     *ModerationUserDTO    `json:"reportedUser,omitempty"`
+	Assignee         *ModerationUserDTO    `json:"assignee,omitempty"`
+	Resolution       string                `json:"resolution,omitempty"`
+	ResolvedAt       *time.Time            `json:"resolvedAt,omitempty"`
+	CreatedAt        time.Time             `json:"createdAt"`
+	UpdatedAt        time.Time             `json:"updatedAt"`
+	OpenReportsCount int                   `json:"openReportsCount,omitempty"` // Laporan terbuka lain terhadap user yang sama
+	Snapshot         json.RawMessage       `json:"snapshot,omitempty"`
+	Actions          []ModerationActionDTO `json:"actions,omitempty"`
+}
+
+// ModerationActionDTO satu tindakan moderator di riwayat laporan
+type ModerationActionDTO struct {
+	ID             string             `json:"id"`
+	Type           string             `json:"type"`
+	Moderator      *ModerationUserDTO `json:"moderator,omitempty"`
+	Note           string             `json:"note,omitempty"`
+	SuspendedUntil *time.Time         `json:"suspendedUntil,omitempty"`
+	CreatedAt      time.Time          `json:"createdAt"`
+}
+
+// ModerationActionInputDTO input tindakan moderator. DurationHours wajib untuk SUSPEND.
+type ModerationActionInputDTO struct {
+	Type          string `json:"type" binding:"required,oneof=DELETE_CONTENT WARN SUSPEND DISBAND_GROUP"`
+	Note          string `json:"note" binding:"max=500"`
+	DurationHours int    `json:"durationHours" binding:"omitempty,min=1,max=87600"`
+}
+
+// ResolveReportDTO input untuk menutup laporan
+type ResolveReportDTO struct {
+	Status     string `json:"status" binding:"required,oneof=RESOLVED DISMISSED"`
+	Resolution string `json:"resolution" binding:"required,max=1000"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // UserDTO (Data Transfer Object) digunakan untuk menangkap data dari request body (JSON)
 // saat user melakukan registrasi atau update profil.
 type UserDTO struct {
@@ -7,6 +9,7 @@ type UserDTO struct {
 	Password string `json:"password" binding:"required,min=6"` // Minimal 6 karakter
 	Name     string `json:"name" binding:"required"`
 	Color    string `json:"color"` // Kode warna hex untuk UI fe
+	Username string `json:"username" binding:"omitempty,min=3,max=30,alphanum"` // Opsional, untuk @mention
 }
 
 // LoginDTO dgunakan untuk validasi login
@@ -21,12 +24,29 @@ type UserResponse struct {
 	ID        string `json:"id"`
 	Email     string `json:"email"`
 	Name      string `json:"name"`
+	Username  string `json:"username,omitempty"`
 	AvatarUrl string `json:"avatarUrl"`
 	Color     string `json:"color"`
 }
 
 // UpdateProfileDTO digunakan untuk memperbarui data dasar profil
 type UpdateProfileDTO struct {
-	Name      string `json:"name" binding:"required"`
-	AvatarUrl string `json:"avatarUrl"`
+	Name      string  `json:"name" binding:"required"`
+	AvatarUrl string  `json:"avatarUrl"`
+	Username  *string `json:"username" binding:"omitempty,min=3,max=30,alphanum"` // nil = tidak diubah
+}
+
+// PresenceDTO adalah status online / terakhir dilihat seorang user
+type PresenceDTO struct {
+	UserID     string     `json:"userId"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
+}
+
+// BlockedUserDTO adalah satu user yang diblokir
+type BlockedUserDTO struct {
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	AvatarUrl string    `json:"avatarUrl"`
+	BlockedAt time.Time `json:"blockedAt"`
 }
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"context"
+	"errors"
+
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+// ErrBroadcastRecipientNotContact dipakai saat penerima daftar broadcast bukan kontak pemilik daftar
+var ErrBroadcastRecipientNotContact = errors.New("penerima broadcast harus ada di kontak Anda")
+
+// ErrBroadcastNoRecipients dipakai saat daftar penerima kosong setelah membuang diri sendiri & duplikat
+var ErrBroadcastNoRecipients = errors.New("daftar broadcast minimal punya satu penerima")
+
+// BroadcastRepository menangani daftar broadcast milik user
+type BroadcastRepository struct {
+	Client *db.PrismaClient
+}
+
+// NewBroadcastRepository inisialisasi repo daftar broadcast
+func NewBroadcastRepository(client *db.PrismaClient) *BroadcastRepository {
+	return &BroadcastRepository{Client: client}
+}
+
+// Create membuat daftar broadcast baru beserta penerimanya
+func (r *BroadcastRepository) Create(ctx context.Context, ownerId, name string, recipientIds []string) (*db.BroadcastListModel, error) {
+	list, err := r.Client.BroadcastList.CreateOne(
+		db.BroadcastList.Name.Set(name),
+		db.BroadcastList.Owner.Link(db.User.ID.Equals(ownerId)),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.SetRecipients(ctx, list.ID, recipientIds); err != nil {
+		_ = r.Delete(ctx, list.ID)
+		return nil, err
+	}
+	return r.GetOwned(ctx, list.ID, ownerId)
+}
+
+// GetByOwner mengambil semua daftar broadcast milik user (terbaru dulu) beserta penerimanya
+func (r *BroadcastRepository) GetByOwner(ctx context.Context, ownerId string) ([]db.BroadcastListModel, error) {
+	return r.Client.BroadcastList.FindMany(
+		db.BroadcastList.OwnerID.Equals(ownerId),
+	).With(
+		db.BroadcastList.Recipients.Fetch().With(
+			db.BroadcastRecipient.User.Fetch(),
+		),
+	).OrderBy(
+		db.BroadcastList.CreatedAt.Order(db.SortOrderDesc),
+	).Exec(ctx)
+}
+
+// GetOwned mengambil satu daftar broadcast milik user beserta penerimanya.
+// Daftar milik user lain dianggap tidak ada (db.ErrNotFound).
+func (r *BroadcastRepository) GetOwned(ctx context.Context, id, ownerId string) (*db.BroadcastListModel, error) {
+	return r.Client.BroadcastList.FindFirst(
+		db.BroadcastList.ID.Equals(id),
+		db.BroadcastList.OwnerID.Equals(ownerId),
+	).With(
+		db.BroadcastList.Recipients.Fetch().With(
+			db.BroadcastRecipient.User.Fetch(),
+		),
+	).Exec(ctx)
+}
+
+// Rename mengubah nama daftar broadcast
+func (r *BroadcastRepository) Rename(ctx context.Context, id, name string) error {
+	_, err := r.Client.BroadcastList.FindUnique(
+		db.BroadcastList.ID.Equals(id),
+	).Update(
+		db.BroadcastList.Name.Set(name),
+	).Exec(ctx)
+	return err
+}
+
+// SetRecipients mengganti seluruh penerima daftar broadcast secara atomik
+func (r *BroadcastRepository) SetRecipients(ctx context.Context, listId string, recipientIds []string) error {
+	ops := []transaction.Param{
+		r.Client.BroadcastRecipient.FindMany(
+			db.BroadcastRecipient.ListID.Equals(listId),
+		).Delete().Tx(),
+	}
+	for _, userId := range recipientIds {
+		ops = append(ops, r.Client.BroadcastRecipient.CreateOne(
+			db.BroadcastRecipient.List.Link(db.BroadcastList.ID.Equals(listId)),
+			db.BroadcastRecipient.User.Link(db.User.ID.Equals(userId)),
+		).Tx())
+	}
+
+	return r.Client.Prisma.Transaction(ops...).Exec(ctx)
+}
+
+// Delete menghapus daftar broadcast (penerimanya ikut terhapus, chat yang sudah terkirim tetap ada)
+func (r *BroadcastRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.Client.BroadcastList.FindUnique(
+		db.BroadcastList.ID.Equals(id),
+	).Delete().Exec(ctx)
+	return err
+}
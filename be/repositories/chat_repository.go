@@ -3,6 +3,47 @@ package repositories
 import (
 	"chat-app-be/prisma/db"
 	"context"
+	"errors"
+	"time"
+
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+// Error bisnis yang dipakai bersama oleh jalur REST dan WebSocket
+var (
+	ErrNotParticipant   = errors.New("user bukan peserta chat ini")
+	ErrMessageNotInChat = errors.New("pesan tidak ditemukan di chat ini")
+	ErrMessageDeleted   = errors.New("pesan sudah dihapus")
+	ErrNotForwardable   = errors.New("pesan ini tidak bisa diteruskan")
+	ErrNotChatAdmin     = errors.New("hanya admin grup yang boleh melakukan aksi ini")
+	ErrPinLimit         = errors.New("batas pesan yang disematkan sudah tercapai")
+	ErrNotGroup         = errors.New("aksi ini hanya berlaku untuk grup")
+	ErrAnnouncementOnly = errors.New("hanya admin yang boleh mengirim pesan di grup ini")
+	ErrChatPinLimit     = errors.New("batas chat yang di-pin sudah tercapai")
+	ErrInvalidThread    = errors.New("pesan ini tidak bisa dijadikan thread")
+	ErrNotPoll          = errors.New("pesan ini bukan polling")
+	ErrPollClosed       = errors.New("polling sudah ditutup")
+	ErrInvalidPollVote  = errors.New("pilihan polling tidak valid")
+	ErrPollAnonymous    = errors.New("pemilih polling anonim tidak dapat dilihat")
+	ErrUserBlocked      = errors.New("tidak dapat berinteraksi dengan user ini")
+	ErrBlockingUser     = errors.New("buka blokir user ini terlebih dahulu")
+	ErrEmptyContent     = errors.New("isi pesan wajib diisi")
+	ErrPayloadRequired  = errors.New("data terstruktur wajib diisi sesuai tipe pesan")
+	ErrForeignMediaURL  = errors.New("URL media harus berasal dari upload aplikasi ini")
+	ErrNoLiveLocation   = errors.New("tidak ada lokasi terkini yang sedang dibagikan di chat ini")
+	ErrDraftTooLong     = errors.New("draft terlalu panjang")
+)
+
+// GroupAction adalah aksi di grup yang diatur oleh matriks izin
+type GroupAction string
+
+const (
+	ActionEditInfo      GroupAction = "edit_info"      // Ubah nama/ikon/deskripsi
+	ActionAddMembers    GroupAction = "add_members"    // Tambah anggota
+	ActionPin           GroupAction = "pin"            // Sematkan pesan
+	ActionSend          GroupAction = "send"           // Kirim pesan
+	ActionManageMembers GroupAction = "manage_members" // Keluarkan anggota, ubah role, ubah izin (selalu khusus admin)
+	ActionDisappearing  GroupAction = "disappearing"   // Atur timer pesan sementara (grup: khusus admin)
 )
 
 // ChatRepository menangani query database untuk pesan dan room chat.
@@ -15,65 +56,340 @@ func NewChatRepository(client *db.PrismaClient) *ChatRepository {
 	return &ChatRepository{Client: client}
 }
 
-// CreateMessage menyimpan pesan baru yang dikirim user via WebSocket atau API
-func (r *ChatRepository) CreateMessage(ctx context.Context, senderId, chatId, content string, msgType db.MessageType) (*db.MessageModel, error) {
+// CreateMessage menyimpan pesan baru yang dikirim user via WebSocket atau API.
+// Field opsional (mis. reply) dikirim lewat extras agar pemanggil lama tidak perlu berubah.
+// Jika chat memakai pesan sementara, expiresAt diisi sesuai timer chat (kecuali pesan INFO).
+func (r *ChatRepository) CreateMessage(ctx context.Context, senderId, chatId, content string, msgType db.MessageType, extras ...db.MessageSetParam) (*db.MessageModel, error) {
+	ops := []db.MessageSetParam{
+		db.Message.Type.Set(msgType),
+	}
+	if msgType != db.MessageTypeInfo {
+		chat, err := r.GetChatByID(ctx, chatId)
+		if err != nil {
+			return nil, err
+		}
+		if chat.DisappearingSeconds > 0 {
+			ops = append(ops, db.Message.ExpiresAt.Set(time.Now().Add(time.Duration(chat.DisappearingSeconds)*time.Second)))
+		}
+	}
+	ops = append(ops, extras...)
+
 	return r.Client.Message.CreateOne(
 		db.Message.Content.Set(content),
 		db.Message.Sender.Link(db.User.ID.Equals(senderId)),
 		db.Message.Chat.Link(db.Chat.ID.Equals(chatId)),
-		db.Message.Type.Set(msgType),
+		ops...,
+	).Exec(ctx)
+}
+
+// GetMessageByID mengambil satu pesan lengkap dengan pengirim dan pesan yang dikutip
+func (r *ChatRepository) GetMessageByID(ctx context.Context, messageId string) (*db.MessageModel, error) {
+	return r.Client.Message.FindUnique(
+		db.Message.ID.Equals(messageId),
+	).With(
+		db.Message.Sender.Fetch(),
+		db.Message.ReplyTo.Fetch().With(
+			db.Message.Sender.Fetch(),
+		),
+		db.Message.Reactions.Fetch(),
+		db.Message.Mentions.Fetch(),
+		db.Message.LinkPreview.Fetch(),
+		db.Message.LiveLocation.Fetch(),
+		db.Message.Poll.Fetch().With(
+			db.Poll.Options.Fetch().OrderBy(
+				db.PollOption.Position.Order(db.SortOrderAsc),
+			).With(
+				db.PollOption.Votes.Fetch(),
+			),
+		),
+	).Exec(ctx)
+}
+
+// FindMessageInChat memastikan pesan dengan ID tertentu memang berada di chat yang sama
+func (r *ChatRepository) FindMessageInChat(ctx context.Context, chatId, messageId string) (*db.MessageModel, error) {
+	msg, err := r.Client.Message.FindFirst(
+		db.Message.ID.Equals(messageId),
+		db.Message.ChatID.Equals(chatId),
+	).Exec(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrMessageNotInChat
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// GetChatByID mengambil data satu chat room
+func (r *ChatRepository) GetChatByID(ctx context.Context, chatId string) (*db.ChatModel, error) {
+	return r.Client.Chat.FindUnique(
+		db.Chat.ID.Equals(chatId),
+	).Exec(ctx)
+}
+
+// GetParticipant mengambil keanggotaan user di chat tertentu (termasuk role)
+func (r *ChatRepository) GetParticipant(ctx context.Context, chatId, userId string) (*db.ParticipantModel, error) {
+	p, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, ErrNotParticipant
+	}
+	return p, err
+}
+
+// IsParticipant mengecek apakah user tergabung di chat tertentu
+func (r *ChatRepository) IsParticipant(ctx context.Context, chatId, userId string) bool {
+	p, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Exec(ctx)
+	return err == nil && p != nil
+}
+
+// GetChatMessages mengambil riwayat pesan dalam satu chat room yang terlihat oleh viewerId,
+// sekalian memuat pesan yang dikutip (reply) beserta pengirimnya untuk preview dan reaksinya.
+func (r *ChatRepository) GetChatMessages(ctx context.Context, chatId, viewerId string) ([]db.MessageModel, error) {
+	return r.Client.Message.FindMany(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.ThreadRootID.IsNull(), // Balasan thread tidak masuk timeline utama
+		visibleTo(viewerId),
+	).With(
+		timelineRelations()...,
+	).OrderBy(
+		db.Message.Timestamp.Order(db.SortOrderDesc),
 	).Exec(ctx)
 }
 
-// GetChatMessages mengambil riwayat pesan dalam satu chat room
-func (r *ChatRepository) GetChatMessages(ctx context.Context, chatId string) ([]db.MessageModel, error) {
+// GetMessagesAround mengambil potongan timeline di sekitar satu pesan (mis. dari hasil pencarian),
+// terbaru dulu seperti GetChatMessages. Balasan thread diarahkan ke pesan root-nya.
+func (r *ChatRepository) GetMessagesAround(ctx context.Context, chatId, viewerId, messageId string, limit int) ([]db.MessageModel, error) {
+	anchor, err := r.FindMessageInChat(ctx, chatId, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if anchor.DroppedByBlock && anchor.SenderID != viewerId {
+		return nil, ErrMessageNotInChat
+	}
+	if rootId, ok := anchor.ThreadRootID(); ok {
+		if anchor, err = r.FindMessageInChat(ctx, chatId, rootId); err != nil {
+			return nil, err
+		}
+	}
+
+	// Separuh sebelum pesan acuan, sisanya pesan acuan & sesudahnya
+	half := limit / 2
+	newer, err := r.Client.Message.FindMany(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.ThreadRootID.IsNull(),
+		db.Message.Timestamp.Gte(anchor.Timestamp),
+		visibleTo(viewerId),
+	).With(
+		timelineRelations()...,
+	).OrderBy(
+		db.Message.Timestamp.Order(db.SortOrderAsc),
+	).Take(limit - half).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	older, err := r.Client.Message.FindMany(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.ThreadRootID.IsNull(),
+		db.Message.Timestamp.Lt(anchor.Timestamp),
+		visibleTo(viewerId),
+	).With(
+		timelineRelations()...,
+	).OrderBy(
+		db.Message.Timestamp.Order(db.SortOrderDesc),
+	).Take(half).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]db.MessageModel, 0, len(newer)+len(older))
+	for i := len(newer) - 1; i >= 0; i-- {
+		messages = append(messages, newer[i])
+	}
+	return append(messages, older...), nil
+}
+
+// GetMessagesBefore mengambil maksimal limit pesan yang terlihat oleh viewer sebelum waktu tertentu
+// (termasuk balasan thread), terbaru dulu. Dipakai sebagai konteks snapshot laporan.
+func (r *ChatRepository) GetMessagesBefore(ctx context.Context, chatId, viewerId string, before time.Time, limit int) ([]db.MessageModel, error) {
 	return r.Client.Message.FindMany(
 		db.Message.ChatID.Equals(chatId),
+		db.Message.Timestamp.Lt(before),
+		visibleTo(viewerId),
+	).With(
+		db.Message.Sender.Fetch(),
 	).OrderBy(
 		db.Message.Timestamp.Order(db.SortOrderDesc),
+	).Take(limit).Exec(ctx)
+}
+
+// GetMessagesByIDs mengambil beberapa pesan sekaligus beserta relasi timeline-nya (urutan tidak dijamin)
+func (r *ChatRepository) GetMessagesByIDs(ctx context.Context, ids []string) ([]db.MessageModel, error) {
+	return r.Client.Message.FindMany(
+		db.Message.ID.In(ids),
+	).With(
+		timelineRelations()...,
 	).Exec(ctx)
 }
 
-// GetUserChats mengambil daftar chat yang diikuti oleh user tertentu
-// GetUserChats mengambil daftar chat yang diikuti oleh user tertentu
-func (r *ChatRepository) GetUserChats(ctx context.Context, userId string) ([]db.ChatModel, error) {
+// visibleTo menyaring pesan yang di-drop karena blokir: pesan tersebut hanya terlihat oleh pengirimnya
+func visibleTo(viewerId string) db.MessageWhereParam {
+	return db.Message.Or(
+		db.Message.DroppedByBlock.Equals(false),
+		db.Message.SenderID.Equals(viewerId),
+	)
+}
+
+// timelineRelations adalah relasi yang dimuat untuk menampilkan pesan di timeline:
+// preview reply, reaksi, pengikut thread, mention, preview link, dan hasil polling.
+func timelineRelations() []db.MessageRelationWith {
+	return []db.MessageRelationWith{
+		db.Message.ReplyTo.Fetch().With(
+			db.Message.Sender.Fetch(),
+		),
+		db.Message.Reactions.Fetch(),
+		db.Message.ThreadFollowers.Fetch().With(
+			db.ThreadFollower.User.Fetch(),
+		),
+		db.Message.Mentions.Fetch(),
+		db.Message.LinkPreview.Fetch(),
+		db.Message.LiveLocation.Fetch(),
+		db.Message.Poll.Fetch().With(
+			db.Poll.Options.Fetch().OrderBy(
+				db.PollOption.Position.Order(db.SortOrderAsc),
+			).With(
+				db.PollOption.Votes.Fetch(),
+			),
+		),
+	}
+}
+
+// GetUserChats mengambil keanggotaan chat user (beserta preferensinya) dan data chat-nya.
+// archived menentukan apakah yang diambil chat arsip atau chat biasa.
+func (r *ChatRepository) GetUserChats(ctx context.Context, userId string, archived bool) ([]db.ParticipantModel, error) {
 	// Kita ambil chat yang diikuti user, sekalian load:
     // 1. Pesan terakhir
     // 2. Participants (untuk nama direct chat)
-    // 3. Unread Messages (untuk badge count)
-	participants, err := r.Client.Participant.FindMany(
+    // (Jumlah unread dihitung terpisah lewat GetUnreadCounts)
+	return r.Client.Participant.FindMany(
 		db.Participant.UserID.Equals(userId),
+		db.Participant.IsArchived.Equals(archived),
 	).With(
 		db.Participant.Chat.Fetch().With(
             // Last Message
-			db.Chat.Messages.Fetch().OrderBy(db.Message.Timestamp.Order(db.SortOrderDesc)).Take(1).With(
+			db.Chat.Messages.Fetch(db.Message.ThreadRootID.IsNull(), visibleTo(userId)).OrderBy(db.Message.Timestamp.Order(db.SortOrderDesc)).Take(1).With(
 				db.Message.Sender.Fetch(),
 			),
             // Participants & User Info (for naming)
             db.Chat.Participants.Fetch().With(
                 db.Participant.User.Fetch(),
             ),
-            // Unread Messages (Filter: Not READ AND Not Sender)
-            db.Chat.Messages.Fetch(
-                db.Message.Status.Not(db.MessageStatusRead),
-                db.Message.SenderID.Not(userId),
-            ),
+			// Pin terbaru untuk ditampilkan di daftar chat
+			db.Chat.Pins.Fetch(db.PinnedMessage.Message.Where(visibleTo(userId))).OrderBy(
+				db.PinnedMessage.PinnedAt.Order(db.SortOrderDesc),
+			).Take(1).With(
+				db.PinnedMessage.Message.Fetch(),
+			),
 		),
 	).Exec(ctx)
+}
+
+// UpdateChatPreferences menyimpan preferensi chat milik user (mute, arsip, pin)
+func (r *ChatRepository) UpdateChatPreferences(ctx context.Context, chatId, userId string, params ...db.ParticipantSetParam) (*db.ParticipantModel, error) {
+	participant, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Update(params...).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, ErrNotParticipant
+	}
+	return participant, err
+}
 
+// ClearDraft menghapus draft user di chat. Mengembalikan true jika sebelumnya ada draft.
+func (r *ChatRepository) ClearDraft(ctx context.Context, chatId, userId string) (bool, error) {
+	res, err := r.Client.Participant.FindMany(
+		db.Participant.UserID.Equals(userId),
+		db.Participant.ChatID.Equals(chatId),
+		db.Participant.Not(db.Participant.DraftUpdatedAt.IsNull()),
+	).Update(draftParams("", "", nil)...).Exec(ctx)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	return res.Count > 0, nil
+}
+
+// draftParams membentuk nilai kolom draft; updatedAt nil berarti draft dikosongkan
+func draftParams(content, replyToId string, updatedAt *time.Time) []db.ParticipantSetParam {
+	return []db.ParticipantSetParam{
+		db.Participant.DraftContent.SetOptional(optionalString(content)),
+		db.Participant.DraftReplyToID.SetOptional(optionalString(replyToId)),
+		db.Participant.DraftUpdatedAt.SetOptional(updatedAt),
 	}
+}
 
-	chats := make([]db.ChatModel, len(participants))
-	for i, p := range participants {
-		chats[i] = *p.Chat()
+// SaveDraft menyimpan draft user di chat. content kosong tanpa replyToId berarti draft dihapus.
+func (r *ChatRepository) SaveDraft(ctx context.Context, chatId, userId, content, replyToId string) (*db.ParticipantModel, error) {
+	var updatedAt *time.Time
+	if content != "" || replyToId != "" {
+		now := time.Now()
+		updatedAt = &now
+	}
+	return r.UpdateChatPreferences(ctx, chatId, userId, draftParams(content, replyToId, updatedAt)...)
+}
+
+// CountPinnedChats menghitung chat yang sedang di-pin user
+func (r *ChatRepository) CountPinnedChats(ctx context.Context, userId string) (int, error) {
+	pinned, err := r.Client.Participant.FindMany(
+		db.Participant.UserID.Equals(userId),
+		db.Participant.IsPinned.Equals(true),
+	).Exec(ctx)
+	return len(pinned), err
+}
+
+// UnarchiveOnNewMessage mengeluarkan chat dari arsip anggota saat ada pesan baru,
+// kecuali anggota yang memilih tetap mengarsipkannya
+func (r *ChatRepository) UnarchiveOnNewMessage(ctx context.Context, chatId string) error {
+	_, err := r.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatId),
+		db.Participant.IsArchived.Equals(true),
+		db.Participant.KeepArchived.Equals(false),
+	).Update(
+		db.Participant.IsArchived.Set(false),
+	).Exec(ctx)
+	return err
+}
+
+// IsMuted mengecek apakah chat sedang di-mute oleh anggota pada waktu tertentu
+func IsMuted(p db.ParticipantModel, now time.Time) bool {
+	if p.MutedForever {
+		return true
+	}
+	until, ok := p.MutedUntil()
+	return ok && until.After(now)
+}
+
+// roleFor menentukan role awal anggota grup baru
+func roleFor(userId, creatorId string) db.ParticipantRole {
+	if userId == creatorId {
+		return db.ParticipantRoleOwner
 	}
-	return chats, nil
+	return db.ParticipantRoleMember
 }
 
-// CreateGroupChat membuat chat room baru untuk banyak orang
-func (r *ChatRepository) CreateGroupChat(ctx context.Context, name string, userIds []string) (*db.ChatModel, error) {
+// CreateGroupChat membuat chat room baru untuk banyak orang, pembuat grup otomatis jadi owner
+func (r *ChatRepository) CreateGroupChat(ctx context.Context, name, creatorId string, userIds []string) (*db.ChatModel, error) {
 	// 1. Buat Chat Room
 	chat, err := r.Client.Chat.CreateOne(
 		db.Chat.Name.Set(name),
@@ -88,6 +404,7 @@ func (r *ChatRepository) CreateGroupChat(ctx context.Context, name string, userI
 		_, err = r.Client.Participant.CreateOne(
 			db.Participant.User.Link(db.User.ID.Equals(uid)),
 			db.Participant.Chat.Link(db.Chat.ID.Equals(chat.ID)),
+			db.Participant.Role.Set(roleFor(uid, creatorId)),
 		).Exec(ctx)
 		// Jika salah satu gagal, kita tetap lanjut (atau bisa di-handle sesuai bisnis logik)
 	}
@@ -128,7 +445,12 @@ func (r *ChatRepository) CreateOrGetDirectChat(ctx context.Context, userId1, use
         }
     }
 
-    // 2. Jika belum ada, buat baru
+    // 2. Chat baru tidak bisa dibuat jika salah satu pihak memblokir yang lain
+    if r.IsBlockedEitherWay(ctx, userId1, userId2) {
+        return nil, ErrUserBlocked
+    }
+
+    // 3. Jika belum ada, buat baru
     chat, err := r.Client.Chat.CreateOne(
         db.Chat.IsGroup.Set(false),
         // Nama optional untuk direct chat, biasanya kosong atau bisa diisi string kombinasi
@@ -152,6 +474,300 @@ func (r *ChatRepository) CreateOrGetDirectChat(ctx context.Context, userId1, use
 
     return chat, nil
 }
+// FindDirectChat mencari chat direct antara dua user tanpa membuat chat baru
+func (r *ChatRepository) FindDirectChat(ctx context.Context, userId1, userId2 string) (*db.ChatModel, error) {
+	return r.Client.Chat.FindFirst(
+		db.Chat.IsGroup.Equals(false),
+		db.Chat.Participants.Some(db.Participant.UserID.Equals(userId1)),
+		db.Chat.Participants.Some(db.Participant.UserID.Equals(userId2)),
+	).Exec(ctx)
+}
+
+// GetUnreadCounts menghitung jumlah pesan belum dibaca per chat milik user,
+// berdasarkan cursor baca masing-masing anggota (bukan status global pesan).
+// Chat tanpa pesan belum dibaca tidak muncul di map.
+func (r *ChatRepository) GetUnreadCounts(ctx context.Context, userId string) (map[string]int, error) {
+	var rows []struct {
+		ChatID db.RawString `json:"chatId"`
+		Count  db.RawInt    `json:"count"`
+	}
+	err := r.Client.Prisma.QueryRaw(`
+		SELECT p."chatId", COUNT(m."id")::int AS "count"
+		FROM "Participant" p
+		LEFT JOIN "Message" lr ON lr."id" = p."lastReadMessageId"
+		JOIN "Message" m ON m."chatId" = p."chatId"
+			AND m."senderId" <> p."userId"
+			AND m."threadRootId" IS NULL
+			AND m."droppedByBlock" = false
+			AND m."timestamp" > COALESCE(lr."timestamp", p."joinedAt")
+		WHERE p."userId" = $1
+		GROUP BY p."chatId"`, userId).Exec(ctx, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[string(row.ChatID)] = int(row.Count)
+	}
+	return counts, nil
+}
+
+// MarkChatRead memajukan cursor baca user di chat sampai messageId (cursor tidak pernah mundur).
+// Mengembalikan pesan yang status-nya baru berubah jadi READ karena semua penerima sudah membacanya.
+func (r *ChatRepository) MarkChatRead(ctx context.Context, chatId, userId, messageId string) (*db.ParticipantModel, []db.MessageModel, error) {
+	participant, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).With(
+		db.Participant.LastReadMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil, ErrNotParticipant
+		}
+		return nil, nil, err
+	}
+
+	target, err := r.FindMessageInChat(ctx, chatId, messageId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current, ok := participant.LastReadMessage(); ok && !target.Timestamp.After(current.Timestamp) {
+		return participant, nil, nil
+	}
+
+	participant, err = r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Update(
+		db.Participant.LastReadMessage.Link(db.Message.ID.Equals(target.ID)),
+		db.Participant.LastReadAt.Set(time.Now()),
+	).Exec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	read, err := r.flushStatus(ctx, chatId, target.Timestamp, db.MessageStatusRead, readCursor,
+		db.MessageStatusSent, db.MessageStatusDelivered)
+	return participant, read, err
+}
+
+// MarkChatDelivered memajukan cursor terima user di chat sampai messageId (tidak pernah mundur).
+// Mengembalikan pesan yang baru sampai ke user ini, serta pesan yang status-nya berubah jadi
+// DELIVERED karena sudah sampai ke semua penerima.
+func (r *ChatRepository) MarkChatDelivered(ctx context.Context, chatId, userId, messageId string) (delivered, flipped []db.MessageModel, err error) {
+	participant, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).With(
+		db.Participant.LastReadMessage.Fetch(),
+		db.Participant.LastDeliveredMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil, ErrNotParticipant
+		}
+		return nil, nil, err
+	}
+
+	target, err := r.FindMessageInChat(ctx, chatId, messageId)
+	if err != nil {
+		return nil, nil, err
+	}
+	since, ok := deliveredCursor(*participant)
+	if !ok {
+		since = participant.JoinedAt
+	}
+	if !target.Timestamp.After(since) {
+		return nil, nil, nil
+	}
+
+	// Cursor hanya boleh maju. Update bersyarat di SQL supaya dua MarkDelivered yang berjalan
+	// bersamaan (goroutine terpisah per pesan) tidak menimpa cursor dengan pesan yang lebih lama.
+	res, err := r.Client.Prisma.ExecuteRaw(`
+		UPDATE "Participant" p
+		SET "lastDeliveredMessageId" = $3, "lastDeliveredAt" = NOW()
+		WHERE p."userId" = $1 AND p."chatId" = $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM "Message" cur
+			WHERE cur."id" = p."lastDeliveredMessageId"
+			  AND cur."timestamp" >= (SELECT "timestamp" FROM "Message" WHERE "id" = $3)
+		  )`, userId, chatId, target.ID).Exec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Count == 0 {
+		// Cursor sudah dimajukan lebih jauh oleh pemanggil lain
+		return nil, nil, nil
+	}
+
+	delivered, err = r.Client.Message.FindMany(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.SenderID.Not(userId),
+		db.Message.DroppedByBlock.Equals(false),
+		db.Message.Timestamp.Gt(since),
+		db.Message.Timestamp.Lte(target.Timestamp),
+	).Exec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flipped, err = r.flushStatus(ctx, chatId, target.Timestamp, db.MessageStatusDelivered, deliveredCursor,
+		db.MessageStatusSent)
+	return delivered, flipped, err
+}
+
+// GetPendingDeliveries mengambil pesan yang belum sampai ke user (di atas cursor terima/baca-nya),
+// dikelompokkan per chat dan dibatasi maksimal limit pesan terlama per chat.
+func (r *ChatRepository) GetPendingDeliveries(ctx context.Context, userId string, limit int) (map[string][]db.MessageModel, error) {
+	participants, err := r.Client.Participant.FindMany(
+		db.Participant.UserID.Equals(userId),
+	).With(
+		db.Participant.LastReadMessage.Fetch(),
+		db.Participant.LastDeliveredMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string][]db.MessageModel)
+	for _, p := range participants {
+		since, ok := deliveredCursor(p)
+		if !ok {
+			since = p.JoinedAt
+		}
+
+		messages, err := r.Client.Message.FindMany(
+			db.Message.ChatID.Equals(p.ChatID),
+			db.Message.SenderID.Not(userId),
+			db.Message.DroppedByBlock.Equals(false),
+			db.Message.Timestamp.Gt(since),
+		).With(
+			db.Message.ReplyTo.Fetch().With(
+				db.Message.Sender.Fetch(),
+			),
+			db.Message.Mentions.Fetch(),
+		).OrderBy(
+			db.Message.Timestamp.Order(db.SortOrderAsc),
+		).Take(limit).Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) > 0 {
+			pending[p.ChatID] = messages
+		}
+	}
+	return pending, nil
+}
+
+// flushStatus menaikkan status pesan (s/d upTo) yang masih berstatus salah satu dari "from"
+// menjadi "status", jika cursor semua penerimanya sudah melewati pesan tersebut
+func (r *ChatRepository) flushStatus(ctx context.Context, chatId string, upTo time.Time, status db.MessageStatus, cursor func(db.ParticipantModel) (time.Time, bool), from ...db.MessageStatus) ([]db.MessageModel, error) {
+	candidates, err := r.Client.Message.FindMany(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.Status.In(from),
+		db.Message.Timestamp.Lte(upTo),
+		db.Message.DroppedByBlock.Equals(false), // Pesan yang di-drop tetap centang satu bagi pengirimnya
+	).Exec(ctx)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	participants, err := r.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatId),
+	).With(
+		db.Participant.LastReadMessage.Fetch(),
+		db.Participant.LastDeliveredMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []db.MessageModel
+	var ids []string
+	for _, m := range candidates {
+		if coveredByAll(m, participants, cursor) {
+			m.Status = status
+			updated = append(updated, m)
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	_, err = r.Client.Message.FindMany(
+		db.Message.ID.In(ids),
+	).Update(
+		db.Message.Status.Set(status),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// coveredByAll mengecek apakah cursor semua penerima pesan sudah melewati pesan.
+// Penerima = anggota selain pengirim yang sudah bergabung saat pesan dikirim; anggota yang
+// bergabung belakangan tidak menahan status pesan lama. Penerima tanpa cursor dianggap belum.
+func coveredByAll(m db.MessageModel, participants []db.ParticipantModel, cursor func(db.ParticipantModel) (time.Time, bool)) bool {
+	for _, p := range participants {
+		if p.UserID == m.SenderID || p.JoinedAt.After(m.Timestamp) {
+			continue
+		}
+		ts, ok := cursor(p)
+		if !ok || ts.Before(m.Timestamp) {
+			return false
+		}
+	}
+	return true
+}
+
+// readCursor mengambil timestamp pesan terakhir yang dibaca anggota
+func readCursor(p db.ParticipantModel) (time.Time, bool) {
+	m, ok := p.LastReadMessage()
+	if !ok {
+		return time.Time{}, false
+	}
+	return m.Timestamp, true
+}
+
+// deliveredCursor mengambil timestamp pesan terakhir yang sampai ke anggota
+// (pesan yang sudah dibaca otomatis dianggap sudah sampai)
+func deliveredCursor(p db.ParticipantModel) (time.Time, bool) {
+	read, readOk := readCursor(p)
+	m, ok := p.LastDeliveredMessage()
+	if !ok {
+		return read, readOk
+	}
+	if readOk && read.After(m.Timestamp) {
+		return read, true
+	}
+	return m.Timestamp, true
+}
+
+// GetMessageReceipts mengambil anggota (selain pengirim) yang cursor bacanya sudah melewati pesan
+func (r *ChatRepository) GetMessageReceipts(ctx context.Context, msg *db.MessageModel) ([]db.ParticipantModel, error) {
+	return r.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(msg.ChatID),
+		db.Participant.UserID.Not(msg.SenderID),
+		db.Participant.LastReadMessage.Where(
+			db.Message.Timestamp.Gte(msg.Timestamp),
+		),
+	).With(
+		db.Participant.User.Fetch(),
+	).OrderBy(
+		db.Participant.LastReadAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
 // UpdateMessageStatus memperbarui status pesan (SENT, DELIVERED, READ)
 func (r *ChatRepository) UpdateMessageStatus(ctx context.Context, messageId string, status db.MessageStatus) (*db.MessageModel, error) {
 	return r.Client.Message.FindUnique(
@@ -160,3 +776,1185 @@ func (r *ChatRepository) UpdateMessageStatus(ctx context.Context, messageId stri
 		db.Message.Status.Set(status),
 	).Exec(ctx)
 }
+
+// AddReaction menambahkan reaksi emoji ke pesan (idempotent per user+emoji)
+func (r *ChatRepository) AddReaction(ctx context.Context, messageId, userId, emoji string) (*db.MessageReactionModel, error) {
+	existing, err := r.Client.MessageReaction.FindUnique(
+		db.MessageReaction.MessageIDUserIDEmoji(
+			db.MessageReaction.MessageID.Equals(messageId),
+			db.MessageReaction.UserID.Equals(userId),
+			db.MessageReaction.Emoji.Equals(emoji),
+		),
+	).Exec(ctx)
+	if err == nil && existing != nil {
+		return existing, nil
+	}
+
+	return r.Client.MessageReaction.CreateOne(
+		db.MessageReaction.Emoji.Set(emoji),
+		db.MessageReaction.Message.Link(db.Message.ID.Equals(messageId)),
+		db.MessageReaction.User.Link(db.User.ID.Equals(userId)),
+	).Exec(ctx)
+}
+
+// RemoveReaction menghapus reaksi emoji milik user dari pesan
+func (r *ChatRepository) RemoveReaction(ctx context.Context, messageId, userId, emoji string) error {
+	_, err := r.Client.MessageReaction.FindUnique(
+		db.MessageReaction.MessageIDUserIDEmoji(
+			db.MessageReaction.MessageID.Equals(messageId),
+			db.MessageReaction.UserID.Equals(userId),
+			db.MessageReaction.Emoji.Equals(emoji),
+		),
+	).Delete().Exec(ctx)
+	// Jika memang belum ada reaksinya, anggap sudah terhapus
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// GetMessageReactions mengambil semua reaksi pada satu pesan
+func (r *ChatRepository) GetMessageReactions(ctx context.Context, messageId string) ([]db.MessageReactionModel, error) {
+	return r.Client.MessageReaction.FindMany(
+		db.MessageReaction.MessageID.Equals(messageId),
+	).OrderBy(
+		db.MessageReaction.CreatedAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
+// PinMessage menyematkan pesan di chat. Jika sudah tersemat, data lama dikembalikan (idempotent).
+func (r *ChatRepository) PinMessage(ctx context.Context, chatId, messageId, userId string, limit int) (*db.PinnedMessageModel, bool, error) {
+	existing, err := r.Client.PinnedMessage.FindUnique(
+		db.PinnedMessage.ChatIDMessageID(
+			db.PinnedMessage.ChatID.Equals(chatId),
+			db.PinnedMessage.MessageID.Equals(messageId),
+		),
+	).Exec(ctx)
+	if err == nil && existing != nil {
+		return existing, false, nil
+	}
+
+	// Cek batas jumlah pin per chat
+	pins, err := r.Client.PinnedMessage.FindMany(
+		db.PinnedMessage.ChatID.Equals(chatId),
+	).Exec(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(pins) >= limit {
+		return nil, false, ErrPinLimit
+	}
+
+	pin, err := r.Client.PinnedMessage.CreateOne(
+		db.PinnedMessage.Chat.Link(db.Chat.ID.Equals(chatId)),
+		db.PinnedMessage.Message.Link(db.Message.ID.Equals(messageId)),
+		db.PinnedMessage.PinnedBy.Link(db.User.ID.Equals(userId)),
+	).Exec(ctx)
+	return pin, true, err
+}
+
+// UnpinMessage melepas pesan yang tersemat di chat
+func (r *ChatRepository) UnpinMessage(ctx context.Context, chatId, messageId string) error {
+	_, err := r.Client.PinnedMessage.FindUnique(
+		db.PinnedMessage.ChatIDMessageID(
+			db.PinnedMessage.ChatID.Equals(chatId),
+			db.PinnedMessage.MessageID.Equals(messageId),
+		),
+	).Delete().Exec(ctx)
+	return err
+}
+
+// GetPinnedMessages mengambil semua pesan tersemat di chat yang terlihat oleh viewerId (terbaru di atas).
+// viewerId kosong berarti hanya pin yang terlihat oleh semua peserta.
+func (r *ChatRepository) GetPinnedMessages(ctx context.Context, chatId, viewerId string) ([]db.PinnedMessageModel, error) {
+	return r.Client.PinnedMessage.FindMany(
+		db.PinnedMessage.ChatID.Equals(chatId),
+		db.PinnedMessage.Message.Where(visibleTo(viewerId)),
+	).With(
+		db.PinnedMessage.Message.Fetch().With(
+			db.Message.Sender.Fetch(),
+			db.Message.Mentions.Fetch(),
+		),
+		db.PinnedMessage.PinnedBy.Fetch(),
+	).OrderBy(
+		db.PinnedMessage.PinnedAt.Order(db.SortOrderDesc),
+	).Exec(ctx)
+}
+
+// StarMessage menandai pesan sebagai favorit milik user (idempotent)
+func (r *ChatRepository) StarMessage(ctx context.Context, userId, messageId string) (*db.StarredMessageModel, error) {
+	existing, err := r.Client.StarredMessage.FindUnique(
+		db.StarredMessage.UserIDMessageID(
+			db.StarredMessage.UserID.Equals(userId),
+			db.StarredMessage.MessageID.Equals(messageId),
+		),
+	).Exec(ctx)
+	if err == nil && existing != nil {
+		return existing, nil
+	}
+
+	return r.Client.StarredMessage.CreateOne(
+		db.StarredMessage.User.Link(db.User.ID.Equals(userId)),
+		db.StarredMessage.Message.Link(db.Message.ID.Equals(messageId)),
+	).Exec(ctx)
+}
+
+// UnstarMessage menghapus tanda favorit user dari pesan
+func (r *ChatRepository) UnstarMessage(ctx context.Context, userId, messageId string) error {
+	_, err := r.Client.StarredMessage.FindUnique(
+		db.StarredMessage.UserIDMessageID(
+			db.StarredMessage.UserID.Equals(userId),
+			db.StarredMessage.MessageID.Equals(messageId),
+		),
+	).Delete().Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// GetStarredMessages mengambil pesan favorit user dari semua chat (terbaru di atas).
+// Filter chatId & msgType opsional. Pesan yang sudah dihapus atau dari chat yang
+// sudah tidak diikuti user tidak ikut ditampilkan.
+func (r *ChatRepository) GetStarredMessages(ctx context.Context, userId, chatId, msgType string, skip, take int) ([]db.StarredMessageModel, error) {
+	msgFilters := []db.MessageWhereParam{
+		db.Message.IsDeleted.Equals(false),
+		db.Message.Chat.Where(
+			db.Chat.Participants.Some(
+				db.Participant.UserID.Equals(userId),
+			),
+		),
+	}
+	if chatId != "" {
+		msgFilters = append(msgFilters, db.Message.ChatID.Equals(chatId))
+	}
+	if msgType != "" {
+		msgFilters = append(msgFilters, db.Message.Type.Equals(db.MessageType(msgType)))
+	}
+
+	return r.Client.StarredMessage.FindMany(
+		db.StarredMessage.UserID.Equals(userId),
+		db.StarredMessage.Message.Where(msgFilters...),
+	).With(
+		db.StarredMessage.Message.Fetch().With(
+			db.Message.Sender.Fetch(),
+			db.Message.Mentions.Fetch(),
+			db.Message.Chat.Fetch().With(
+				db.Chat.Participants.Fetch().With(
+					db.Participant.User.Fetch(),
+				),
+			),
+		),
+	).OrderBy(
+		db.StarredMessage.CreatedAt.Order(db.SortOrderDesc),
+	).Skip(skip).Take(take).Exec(ctx)
+}
+
+// GetUsersByIDs mengambil data beberapa user sekaligus (untuk nama di pesan INFO)
+func (r *ChatRepository) GetUsersByIDs(ctx context.Context, userIds []string) ([]db.UserModel, error) {
+	return r.Client.User.FindMany(
+		db.User.ID.In(userIds),
+	).Exec(ctx)
+}
+
+// AddParticipants menambahkan anggota baru ke grup, user yang sudah jadi anggota dilewati.
+// Mengembalikan ID user yang benar-benar ditambahkan.
+func (r *ChatRepository) AddParticipants(ctx context.Context, chatId string, userIds []string) ([]string, error) {
+	var added []string
+	for _, uid := range userIds {
+		if r.IsParticipant(ctx, chatId, uid) {
+			continue
+		}
+		_, err := r.Client.Participant.CreateOne(
+			db.Participant.User.Link(db.User.ID.Equals(uid)),
+			db.Participant.Chat.Link(db.Chat.ID.Equals(chatId)),
+		).Exec(ctx)
+		if err != nil {
+			return added, err
+		}
+		added = append(added, uid)
+	}
+	return added, nil
+}
+
+// RemoveParticipant mengeluarkan user dari chat. Sejak saat itu user tidak bisa
+// lagi membaca riwayat maupun menerima pesan baru dari chat ini.
+func (r *ChatRepository) RemoveParticipant(ctx context.Context, chatId, userId string) error {
+	_, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Delete().Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return ErrNotParticipant
+	}
+	return err
+}
+
+// DeleteMessageForEveryone menandai pesan terhapus untuk semua peserta dan mengosongkan isinya.
+// Pin pesan ikut dilepas. Dipakai moderator untuk menurunkan konten yang dilaporkan.
+func (r *ChatRepository) DeleteMessageForEveryone(ctx context.Context, messageId string) (*db.MessageModel, error) {
+	msg, err := r.Client.Message.FindUnique(
+		db.Message.ID.Equals(messageId),
+	).Update(
+		db.Message.IsDeleted.Set(true),
+		db.Message.Content.Set(""),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.Client.PinnedMessage.FindMany(
+		db.PinnedMessage.MessageID.Equals(messageId),
+	).Delete().Exec(ctx)
+	return msg, err
+}
+
+// DisbandGroup membubarkan grup: semua anggota dikeluarkan, undangan dicabut, dan permintaan
+// bergabung dihapus, sehingga tidak ada lagi yang bisa masuk. Riwayat pesan tetap disimpan.
+// Mengembalikan ID anggota sebelum grup dibubarkan.
+func (r *ChatRepository) DisbandGroup(ctx context.Context, chatId string) ([]string, error) {
+	participants, err := r.GetParticipants(ctx, chatId)
+	if err != nil {
+		return nil, err
+	}
+	memberIds := make([]string, len(participants))
+	for i, p := range participants {
+		memberIds[i] = p.UserID
+	}
+
+	err = r.Client.Prisma.Transaction(
+		r.Client.GroupInvite.FindMany(
+			db.GroupInvite.ChatID.Equals(chatId),
+		).Delete().Tx(),
+		r.Client.GroupJoinRequest.FindMany(
+			db.GroupJoinRequest.ChatID.Equals(chatId),
+		).Delete().Tx(),
+		r.Client.Participant.FindMany(
+			db.Participant.ChatID.Equals(chatId),
+		).Delete().Tx(),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memberIds, nil
+}
+
+// EnsureGroupOwner memastikan grup selalu punya owner. Jika owner keluar, kepemilikan
+// dialihkan ke admin paling lama, atau anggota paling lama jika tidak ada admin.
+// Mengembalikan anggota yang menjadi owner baru (nil jika tidak ada perubahan).
+func (r *ChatRepository) EnsureGroupOwner(ctx context.Context, chatId string) (*db.ParticipantModel, error) {
+	participants, err := r.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatId),
+	).With(
+		db.Participant.User.Fetch(),
+	).OrderBy(
+		db.Participant.JoinedAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+	if err != nil || len(participants) == 0 {
+		return nil, err
+	}
+
+	var successor *db.ParticipantModel
+	for i, p := range participants {
+		if p.Role == db.ParticipantRoleOwner {
+			return nil, nil
+		}
+		if p.Role == db.ParticipantRoleAdmin && successor == nil {
+			successor = &participants[i]
+		}
+	}
+	if successor == nil {
+		successor = &participants[0]
+	}
+
+	if _, err := r.SetParticipantRole(ctx, chatId, successor.UserID, db.ParticipantRoleOwner); err != nil {
+		return nil, err
+	}
+	return successor, nil
+}
+
+// IsGroupAdmin true untuk role OWNER dan ADMIN
+func IsGroupAdmin(role db.ParticipantRole) bool {
+	return role == db.ParticipantRoleOwner || role == db.ParticipantRoleAdmin
+}
+
+// IsBlocked mengecek apakah blockerId memblokir blockedId
+func (r *ChatRepository) IsBlocked(ctx context.Context, blockerId, blockedId string) bool {
+	return isBlocked(ctx, r.Client, blockerId, blockedId)
+}
+
+// IsBlockedEitherWay mengecek apakah salah satu dari dua user memblokir yang lain
+func (r *ChatRepository) IsBlockedEitherWay(ctx context.Context, userId1, userId2 string) bool {
+	return isBlocked(ctx, r.Client, userId1, userId2) || isBlocked(ctx, r.Client, userId2, userId1)
+}
+
+// BlockerIDs mengembalikan himpunan user yang memblokir userId (foto profil dll. disembunyikan darinya)
+func (r *ChatRepository) BlockerIDs(ctx context.Context, userId string) (map[string]bool, error) {
+	ids, err := blockerIDs(ctx, r.Client, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// BlockedByAny mengecek apakah ada di antara userIds yang memblokir userId
+// (dipakai agar user tidak bisa menambahkan orang yang memblokirnya ke grup)
+func (r *ChatRepository) BlockedByAny(ctx context.Context, userId string, userIds []string) (bool, error) {
+	blocks, err := r.Client.Block.FindMany(
+		db.Block.BlockedID.Equals(userId),
+		db.Block.BlockerID.In(userIds),
+	).Take(1).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(blocks) > 0, nil
+}
+
+// GetDirectPeerID mengembalikan ID lawan bicara di chat direct (false untuk grup)
+func (r *ChatRepository) GetDirectPeerID(ctx context.Context, chatId, userId string) (string, bool) {
+	peer, err := r.Client.Participant.FindFirst(
+		db.Participant.ChatID.Equals(chatId),
+		db.Participant.UserID.Not(userId),
+		db.Participant.Chat.Where(db.Chat.IsGroup.Equals(false)),
+	).Exec(ctx)
+	if err != nil {
+		return "", false
+	}
+	return peer.UserID, true
+}
+
+// CheckPermission memeriksa apakah user boleh melakukan aksi di chat sesuai matriks izin grup.
+// Dipakai oleh REST (ChatController/GroupController) maupun jalur kirim WebSocket.
+// Mengembalikan data keanggotaan user (beserta chat-nya) jika diizinkan.
+func (r *ChatRepository) CheckPermission(ctx context.Context, chatId, userId string, action GroupAction) (*db.ParticipantModel, error) {
+	participant, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).With(
+		db.Participant.Chat.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrNotParticipant
+		}
+		return nil, err
+	}
+
+	// Chat direct: semua peserta setara, tapi aksi khusus grup tidak berlaku
+	chat := participant.Chat()
+	if !chat.IsGroup {
+		if action == ActionSend || action == ActionPin || action == ActionDisappearing {
+			return participant, nil
+		}
+		return nil, ErrNotGroup
+	}
+
+	if IsGroupAdmin(participant.Role) {
+		return participant, nil
+	}
+
+	permission := db.GroupPermissionAdmins
+	switch action {
+	case ActionEditInfo:
+		permission = chat.EditInfoPermission
+	case ActionAddMembers:
+		permission = chat.AddMembersPermission
+	case ActionPin:
+		permission = chat.PinPermission
+	case ActionSend:
+		permission = chat.SendPermission
+	}
+	if permission == db.GroupPermissionAll {
+		return participant, nil
+	}
+	if action == ActionSend {
+		return nil, ErrAnnouncementOnly
+	}
+	return nil, ErrNotChatAdmin
+}
+
+// SetParticipantRole mengubah role anggota grup
+func (r *ChatRepository) SetParticipantRole(ctx context.Context, chatId, userId string, role db.ParticipantRole) (*db.ParticipantModel, error) {
+	return r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Update(
+		db.Participant.Role.Set(role),
+	).Exec(ctx)
+}
+
+// TransferOwnership memindahkan kepemilikan grup secara atomik: owner lama menjadi ADMIN
+func (r *ChatRepository) TransferOwnership(ctx context.Context, chatId, fromUserId, toUserId string) error {
+	demote := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(fromUserId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Update(
+		db.Participant.Role.Set(db.ParticipantRoleAdmin),
+	).Tx()
+
+	promote := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(toUserId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Update(
+		db.Participant.Role.Set(db.ParticipantRoleOwner),
+	).Tx()
+
+	return r.Client.Prisma.Transaction(demote, promote).Exec(ctx)
+}
+
+// GetParticipants mengambil semua anggota chat beserta data user-nya (paling lama bergabung di atas)
+func (r *ChatRepository) GetParticipants(ctx context.Context, chatId string) ([]db.ParticipantModel, error) {
+	return r.Client.Participant.FindMany(
+		db.Participant.ChatID.Equals(chatId),
+	).With(
+		db.Participant.User.Fetch(),
+	).OrderBy(
+		db.Participant.JoinedAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
+// UpdateGroupPermissions memperbarui matriks izin grup & mode persetujuan join (field nil tidak diubah)
+func (r *ChatRepository) UpdateGroupPermissions(ctx context.Context, chatId string, editInfo, addMembers, pin, send *db.GroupPermission, joinApproval *bool) (*db.ChatModel, error) {
+	return r.Client.Chat.FindUnique(
+		db.Chat.ID.Equals(chatId),
+	).Update(
+		db.Chat.EditInfoPermission.SetIfPresent(editInfo),
+		db.Chat.AddMembersPermission.SetIfPresent(addMembers),
+		db.Chat.PinPermission.SetIfPresent(pin),
+		db.Chat.SendPermission.SetIfPresent(send),
+		db.Chat.JoinApproval.SetIfPresent(joinApproval),
+	).Exec(ctx)
+}
+
+// UpdateGroupInfo memperbarui nama, ikon, dan/atau deskripsi grup (field nil tidak diubah)
+func (r *ChatRepository) UpdateGroupInfo(ctx context.Context, chatId string, name, icon, description *string) (*db.ChatModel, error) {
+	return r.Client.Chat.FindUnique(
+		db.Chat.ID.Equals(chatId),
+	).Update(
+		db.Chat.Name.SetIfPresent(name),
+		db.Chat.Icon.SetIfPresent(icon),
+		db.Chat.Description.SetIfPresent(description),
+	).Exec(ctx)
+}
+
+// SetDisappearingTimer mengubah TTL pesan sementara untuk chat (0 = nonaktif).
+// Hanya berlaku untuk pesan baru; pesan lama tetap dengan expiresAt masing-masing.
+func (r *ChatRepository) SetDisappearingTimer(ctx context.Context, chatId string, seconds int) (*db.ChatModel, error) {
+	return r.Client.Chat.FindUnique(
+		db.Chat.ID.Equals(chatId),
+	).Update(
+		db.Chat.DisappearingSeconds.Set(seconds),
+	).Exec(ctx)
+}
+
+// DeleteExpiredMessages menghapus permanen (hard delete) maksimal limit pesan yang sudah kedaluwarsa.
+// Reaksi, pin, dan favorit ikut terhapus (cascade); reply dan threadRootId balasan thread menjadi null,
+// jadi balasan dari root yang kedaluwarsa kembali tampil di timeline utama. Cursor baca/terima yang
+// menunjuk pesan ini dimundurkan dulu ke pesan tersisa sebelumnya (lihat rewindCursors).
+func (r *ChatRepository) DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]db.MessageModel, error) {
+	expired, err := r.Client.Message.FindMany(
+		db.Message.ExpiresAt.Lte(now),
+	).With(
+		db.Message.MediaUpload.Fetch(),
+	).OrderBy(
+		db.Message.ExpiresAt.Order(db.SortOrderAsc),
+	).Take(limit).Exec(ctx)
+	if err != nil || len(expired) == 0 {
+		return nil, err
+	}
+
+	ids := make([]string, len(expired))
+	var pollIds []string
+	for i, m := range expired {
+		ids[i] = m.ID
+		if pollId, ok := m.PollID(); ok {
+			pollIds = append(pollIds, pollId)
+		}
+	}
+	if err := r.rewindCursors(ctx, ids); err != nil {
+		return nil, err
+	}
+	if _, err := r.Client.Message.FindMany(
+		db.Message.ID.In(ids),
+	).Delete().Exec(ctx); err != nil {
+		return nil, err
+	}
+	// Poll tidak ikut terhapus otomatis karena relasinya disimpan di pesan
+	if len(pollIds) > 0 {
+		if _, err := r.Client.Poll.FindMany(
+			db.Poll.ID.In(pollIds),
+		).Delete().Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
+
+// rewindCursors memindahkan cursor baca & terima yang menunjuk salah satu pesan yang akan dihapus ke
+// pesan tersisa terbaru di chat yang sama pada/sebelum pesan tersebut. Tanpa ini cursor menjadi null
+// (onDelete: SetNull) dan jatuh kembali ke joinedAt, sehingga riwayat chat dengan pesan sementara
+// dianggap belum dibaca & dikirim ulang saat reconnect. Jika tidak ada pesan tersisa sebelumnya,
+// cursor dibiarkan null karena joinedAt sudah setara.
+func (r *ChatRepository) rewindCursors(ctx context.Context, deletedIds []string) error {
+	readers, err := r.Client.Participant.FindMany(
+		db.Participant.LastReadMessageID.In(deletedIds),
+	).With(
+		db.Participant.LastReadMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range readers {
+		cursor, ok := p.LastReadMessage()
+		if !ok {
+			continue
+		}
+		prev, err := r.latestSurvivingMessage(ctx, p.ChatID, cursor.Timestamp, deletedIds)
+		if err != nil {
+			return err
+		}
+		if prev == nil {
+			continue
+		}
+		if _, err := r.Client.Participant.FindUnique(
+			db.Participant.UserIDChatID(
+				db.Participant.UserID.Equals(p.UserID),
+				db.Participant.ChatID.Equals(p.ChatID),
+			),
+		).Update(
+			db.Participant.LastReadMessage.Link(db.Message.ID.Equals(prev.ID)),
+		).Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	receivers, err := r.Client.Participant.FindMany(
+		db.Participant.LastDeliveredMessageID.In(deletedIds),
+	).With(
+		db.Participant.LastDeliveredMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range receivers {
+		cursor, ok := p.LastDeliveredMessage()
+		if !ok {
+			continue
+		}
+		prev, err := r.latestSurvivingMessage(ctx, p.ChatID, cursor.Timestamp, deletedIds)
+		if err != nil {
+			return err
+		}
+		if prev == nil {
+			continue
+		}
+		if _, err := r.Client.Participant.FindUnique(
+			db.Participant.UserIDChatID(
+				db.Participant.UserID.Equals(p.UserID),
+				db.Participant.ChatID.Equals(p.ChatID),
+			),
+		).Update(
+			db.Participant.LastDeliveredMessage.Link(db.Message.ID.Equals(prev.ID)),
+		).Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestSurvivingMessage mengambil pesan terbaru di chat pada/sebelum at yang tidak ikut dihapus
+// (nil jika tidak ada)
+func (r *ChatRepository) latestSurvivingMessage(ctx context.Context, chatId string, at time.Time, deletedIds []string) (*db.MessageModel, error) {
+	msg, err := r.Client.Message.FindFirst(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.ID.NotIn(deletedIds),
+		db.Message.Timestamp.Lte(at),
+	).OrderBy(
+		db.Message.Timestamp.Order(db.SortOrderDesc),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	return msg, err
+}
+
+// ResolveThreadRoot memvalidasi pesan yang akan dijadikan root thread.
+// Jika pesan tersebut sendiri balasan thread, root aslinya yang dipakai (thread hanya satu tingkat).
+func (r *ChatRepository) ResolveThreadRoot(ctx context.Context, chatId, messageId string) (*db.MessageModel, error) {
+	root, err := r.FindMessageInChat(ctx, chatId, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if rootId, ok := root.ThreadRootID(); ok {
+		if root, err = r.FindMessageInChat(ctx, chatId, rootId); err != nil {
+			return nil, err
+		}
+	}
+	if root.IsDeleted || root.Type == db.MessageTypeInfo {
+		return nil, ErrInvalidThread
+	}
+	return root, nil
+}
+
+// RecordThreadReply memperbarui ringkasan root dan pengikut thread setelah ada balasan baru.
+// Penulis root dan pembalas otomatis mengikuti; unread pengikut lain bertambah.
+func (r *ChatRepository) RecordThreadReply(ctx context.Context, root *db.MessageModel, reply *db.MessageModel) error {
+	if _, err := r.Client.Message.FindUnique(
+		db.Message.ID.Equals(root.ID),
+	).Update(
+		db.Message.ThreadReplyCount.Increment(1),
+		db.Message.ThreadLastReplyAt.Set(reply.Timestamp),
+	).Exec(ctx); err != nil {
+		return err
+	}
+
+	if _, err := r.FollowThread(ctx, root.ID, root.SenderID); err != nil {
+		return err
+	}
+	if _, err := r.Client.ThreadFollower.UpsertOne(
+		db.ThreadFollower.RootIDUserID(
+			db.ThreadFollower.RootID.Equals(root.ID),
+			db.ThreadFollower.UserID.Equals(reply.SenderID),
+		),
+	).Create(
+		db.ThreadFollower.Root.Link(db.Message.ID.Equals(root.ID)),
+		db.ThreadFollower.User.Link(db.User.ID.Equals(reply.SenderID)),
+		db.ThreadFollower.HasReplied.Set(true),
+		db.ThreadFollower.LastReadAt.Set(reply.Timestamp),
+	).Update(
+		db.ThreadFollower.HasReplied.Set(true),
+		db.ThreadFollower.UnreadCount.Set(0),
+		db.ThreadFollower.LastReadAt.Set(reply.Timestamp),
+	).Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.Client.ThreadFollower.FindMany(
+		db.ThreadFollower.RootID.Equals(root.ID),
+		db.ThreadFollower.UserID.Not(reply.SenderID),
+	).Update(
+		db.ThreadFollower.UnreadCount.Increment(1),
+	).Exec(ctx)
+	return err
+}
+
+// FollowThread membuat user mengikuti thread (idempotent)
+func (r *ChatRepository) FollowThread(ctx context.Context, rootId, userId string) (*db.ThreadFollowerModel, error) {
+	return r.Client.ThreadFollower.UpsertOne(
+		db.ThreadFollower.RootIDUserID(
+			db.ThreadFollower.RootID.Equals(rootId),
+			db.ThreadFollower.UserID.Equals(userId),
+		),
+	).Create(
+		db.ThreadFollower.Root.Link(db.Message.ID.Equals(rootId)),
+		db.ThreadFollower.User.Link(db.User.ID.Equals(userId)),
+	).Update().Exec(ctx)
+}
+
+// UnfollowThread berhenti mengikuti thread sehingga tidak lagi menerima notifikasi balasan
+func (r *ChatRepository) UnfollowThread(ctx context.Context, rootId, userId string) error {
+	_, err := r.Client.ThreadFollower.FindMany(
+		db.ThreadFollower.RootID.Equals(rootId),
+		db.ThreadFollower.UserID.Equals(userId),
+	).Delete().Exec(ctx)
+	return err
+}
+
+// MarkThreadRead mereset jumlah balasan thread yang belum dibaca user
+func (r *ChatRepository) MarkThreadRead(ctx context.Context, rootId, userId string) error {
+	_, err := r.Client.ThreadFollower.FindMany(
+		db.ThreadFollower.RootID.Equals(rootId),
+		db.ThreadFollower.UserID.Equals(userId),
+	).Update(
+		db.ThreadFollower.UnreadCount.Set(0),
+		db.ThreadFollower.LastReadAt.Set(time.Now()),
+	).Exec(ctx)
+	return err
+}
+
+// GetThreadFollowers mengambil pengikut thread beserta datanya (untuk notifikasi & ringkasan)
+func (r *ChatRepository) GetThreadFollowers(ctx context.Context, rootId string) ([]db.ThreadFollowerModel, error) {
+	return r.Client.ThreadFollower.FindMany(
+		db.ThreadFollower.RootID.Equals(rootId),
+	).With(
+		db.ThreadFollower.User.Fetch(),
+	).Exec(ctx)
+}
+
+// GetThreadReplies mengambil balasan thread yang terlihat oleh viewerId (terlama dulu) dengan pagination
+func (r *ChatRepository) GetThreadReplies(ctx context.Context, rootId, viewerId string, skip, take int) ([]db.MessageModel, error) {
+	return r.Client.Message.FindMany(
+		db.Message.ThreadRootID.Equals(rootId),
+		visibleTo(viewerId),
+	).With(
+		db.Message.ReplyTo.Fetch().With(
+			db.Message.Sender.Fetch(),
+		),
+		db.Message.Reactions.Fetch(),
+		db.Message.Mentions.Fetch(),
+		db.Message.LinkPreview.Fetch(),
+		db.Message.LiveLocation.Fetch(),
+		db.Message.Poll.Fetch().With(
+			db.Poll.Options.Fetch().OrderBy(
+				db.PollOption.Position.Order(db.SortOrderAsc),
+			).With(
+				db.PollOption.Votes.Fetch(),
+			),
+		),
+	).OrderBy(
+		db.Message.Timestamp.Order(db.SortOrderAsc),
+	).Skip(skip).Take(take).Exec(ctx)
+}
+
+// CreatePoll menyimpan polling beserta opsinya (urutan sesuai input).
+// Pesan POLL-nya dibuat terpisah lewat jalur kirim pesan biasa.
+func (r *ChatRepository) CreatePoll(ctx context.Context, question string, options []string, allowMultiple, anonymous bool, closesAt *time.Time) (*db.PollModel, error) {
+	poll, err := r.Client.Poll.CreateOne(
+		db.Poll.Question.Set(question),
+		db.Poll.AllowMultiple.Set(allowMultiple),
+		db.Poll.IsAnonymous.Set(anonymous),
+		db.Poll.ClosesAt.SetIfPresent(closesAt),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, text := range options {
+		if _, err := r.Client.PollOption.CreateOne(
+			db.PollOption.Text.Set(text),
+			db.PollOption.Position.Set(i),
+			db.PollOption.Poll.Link(db.Poll.ID.Equals(poll.ID)),
+		).Exec(ctx); err != nil {
+			_ = r.DeletePoll(ctx, poll.ID)
+			return nil, err
+		}
+	}
+	return poll, nil
+}
+
+// DeletePoll menghapus polling (opsi & suaranya ikut terhapus)
+func (r *ChatRepository) DeletePoll(ctx context.Context, pollId string) error {
+	_, err := r.Client.Poll.FindUnique(
+		db.Poll.ID.Equals(pollId),
+	).Delete().Exec(ctx)
+	return err
+}
+
+// GetPoll mengambil polling beserta opsi (terurut) dan suaranya
+func (r *ChatRepository) GetPoll(ctx context.Context, pollId string) (*db.PollModel, error) {
+	return r.Client.Poll.FindUnique(
+		db.Poll.ID.Equals(pollId),
+	).With(
+		db.Poll.Options.Fetch().OrderBy(
+			db.PollOption.Position.Order(db.SortOrderAsc),
+		).With(
+			db.PollOption.Votes.Fetch(),
+		),
+	).Exec(ctx)
+}
+
+// IsPollClosed mengecek apakah polling sudah ditutup manual atau melewati waktu tutupnya
+func IsPollClosed(p db.PollModel, now time.Time) bool {
+	if _, ok := p.ClosedAt(); ok {
+		return true
+	}
+	if v, ok := p.ClosesAt(); ok && !v.After(now) {
+		return true
+	}
+	return false
+}
+
+// VotePoll mengganti suara user pada polling secara atomik.
+// optionIds kosong berarti menarik suara; polling pilihan tunggal hanya menerima satu opsi.
+func (r *ChatRepository) VotePoll(ctx context.Context, poll *db.PollModel, userId string, optionIds []string) error {
+	if IsPollClosed(*poll, time.Now()) {
+		return ErrPollClosed
+	}
+
+	valid := make(map[string]bool)
+	for _, o := range poll.Options() {
+		valid[o.ID] = true
+	}
+	seen := make(map[string]bool)
+	var chosen []string
+	for _, id := range optionIds {
+		if !valid[id] {
+			return ErrInvalidPollVote
+		}
+		if !seen[id] {
+			seen[id] = true
+			chosen = append(chosen, id)
+		}
+	}
+	if !poll.AllowMultiple && len(chosen) > 1 {
+		return ErrInvalidPollVote
+	}
+
+	ops := []transaction.Param{
+		r.Client.PollVote.FindMany(
+			db.PollVote.PollID.Equals(poll.ID),
+			db.PollVote.UserID.Equals(userId),
+		).Delete().Tx(),
+	}
+	for _, optionId := range chosen {
+		ops = append(ops, r.Client.PollVote.CreateOne(
+			db.PollVote.Poll.Link(db.Poll.ID.Equals(poll.ID)),
+			db.PollVote.Option.Link(db.PollOption.ID.Equals(optionId)),
+			db.PollVote.User.Link(db.User.ID.Equals(userId)),
+		).Tx())
+	}
+	return r.Client.Prisma.Transaction(ops...).Exec(ctx)
+}
+
+// ClosePoll menutup polling sehingga tidak bisa dipilih lagi
+func (r *ChatRepository) ClosePoll(ctx context.Context, pollId string) error {
+	_, err := r.Client.Poll.FindMany(
+		db.Poll.ID.Equals(pollId),
+		db.Poll.ClosedAt.IsNull(),
+	).Update(
+		db.Poll.ClosedAt.Set(time.Now()),
+	).Exec(ctx)
+	return err
+}
+
+// GetPollVoters mengambil semua suara polling beserta data pemilihnya (terlama dulu)
+func (r *ChatRepository) GetPollVoters(ctx context.Context, pollId string) ([]db.PollVoteModel, error) {
+	return r.Client.PollVote.FindMany(
+		db.PollVote.PollID.Equals(pollId),
+	).With(
+		db.PollVote.User.Fetch(),
+	).OrderBy(
+		db.PollVote.CreatedAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
+// ResolveMentions mengubah username hasil parsing menjadi ID anggota chat yang valid.
+// @all berarti semua anggota; pengirim dan username yang bukan anggota diabaikan.
+func (r *ChatRepository) ResolveMentions(ctx context.Context, chatId, senderId string, usernames []string, all bool) ([]string, error) {
+	var ids []string
+	if all {
+		participants, err := r.Client.Participant.FindMany(
+			db.Participant.ChatID.Equals(chatId),
+			db.Participant.UserID.Not(senderId),
+		).Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range participants {
+			ids = append(ids, p.UserID)
+		}
+		return ids, nil
+	}
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	users, err := r.Client.User.FindMany(
+		db.User.Username.In(usernames),
+		db.User.ID.Not(senderId),
+		db.User.Chats.Some(
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+// SaveMentions menyimpan daftar user yang di-mention pada pesan secara atomik
+func (r *ChatRepository) SaveMentions(ctx context.Context, messageId string, userIds []string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	ops := make([]transaction.Param, len(userIds))
+	for i, userId := range userIds {
+		ops[i] = r.Client.MessageMention.CreateOne(
+			db.MessageMention.Message.Link(db.Message.ID.Equals(messageId)),
+			db.MessageMention.User.Link(db.User.ID.Equals(userId)),
+		).Tx()
+	}
+	return r.Client.Prisma.Transaction(ops...).Exec(ctx)
+}
+
+// GetUnreadMentionCounts menghitung mention yang belum dibaca per chat milik user
+// (pesan setelah cursor baca yang me-mention user). Chat tanpa mention tidak muncul di map.
+func (r *ChatRepository) GetUnreadMentionCounts(ctx context.Context, userId string) (map[string]int, error) {
+	var rows []struct {
+		ChatID db.RawString `json:"chatId"`
+		Count  db.RawInt    `json:"count"`
+	}
+	err := r.Client.Prisma.QueryRaw(`
+		SELECT p."chatId", COUNT(m."id")::int AS "count"
+		FROM "Participant" p
+		LEFT JOIN "Message" lr ON lr."id" = p."lastReadMessageId"
+		JOIN "MessageMention" mm ON mm."userId" = p."userId"
+		JOIN "Message" m ON m."id" = mm."messageId"
+			AND m."chatId" = p."chatId"
+			AND m."isDeleted" = false
+			AND m."timestamp" > COALESCE(lr."timestamp", p."joinedAt")
+		WHERE p."userId" = $1
+		GROUP BY p."chatId"`, userId).Exec(ctx, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[string(row.ChatID)] = int(row.Count)
+	}
+	return counts, nil
+}
+
+// GetNextUnreadMention mengambil pesan tertua yang me-mention user setelah cursor bacanya.
+// afterId (opsional) dipakai untuk lompat ke mention berikutnya tanpa memajukan cursor baca.
+// Mengembalikan nil jika tidak ada mention yang belum dibaca.
+func (r *ChatRepository) GetNextUnreadMention(ctx context.Context, chatId, userId, afterId string) (*db.MessageModel, error) {
+	participant, err := r.Client.Participant.FindUnique(
+		db.Participant.UserIDChatID(
+			db.Participant.UserID.Equals(userId),
+			db.Participant.ChatID.Equals(chatId),
+		),
+	).With(
+		db.Participant.LastReadMessage.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrNotParticipant
+		}
+		return nil, err
+	}
+
+	since := participant.JoinedAt
+	if t, ok := readCursor(*participant); ok {
+		since = t
+	}
+	if afterId != "" {
+		after, err := r.FindMessageInChat(ctx, chatId, afterId)
+		if err != nil {
+			return nil, err
+		}
+		if after.Timestamp.After(since) {
+			since = after.Timestamp
+		}
+	}
+
+	msg, err := r.Client.Message.FindFirst(
+		db.Message.ChatID.Equals(chatId),
+		db.Message.IsDeleted.Equals(false),
+		db.Message.Timestamp.Gt(since),
+		db.Message.Mentions.Some(
+			db.MessageMention.UserID.Equals(userId),
+		),
+	).With(
+		db.Message.ReplyTo.Fetch().With(
+			db.Message.Sender.Fetch(),
+		),
+		db.Message.Reactions.Fetch(),
+		db.Message.Mentions.Fetch(),
+		db.Message.LinkPreview.Fetch(),
+		db.Message.LiveLocation.Fetch(),
+	).OrderBy(
+		db.Message.Timestamp.Order(db.SortOrderAsc),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	return msg, err
+}
+
+// GetLinkPreview mengambil cache preview untuk URL (db.ErrNotFound jika belum ada)
+func (r *ChatRepository) GetLinkPreview(ctx context.Context, url string) (*db.LinkPreviewModel, error) {
+	return r.Client.LinkPreview.FindUnique(
+		db.LinkPreview.URL.Equals(url),
+	).Exec(ctx)
+}
+
+// SaveLinkPreview menyimpan/menyegarkan cache preview URL. failed berarti pengambilan gagal
+// (disimpan juga supaya URL yang sama tidak terus diambil ulang sampai cache kedaluwarsa).
+func (r *ChatRepository) SaveLinkPreview(ctx context.Context, url, titleText, descriptionText, imageURL, siteNameText string, failed bool) (*db.LinkPreviewModel, error) {
+	title := optionalString(titleText)
+	description := optionalString(descriptionText)
+	imageUrl := optionalString(imageURL)
+	siteName := optionalString(siteNameText)
+
+	return r.Client.LinkPreview.UpsertOne(
+		db.LinkPreview.URL.Equals(url),
+	).Create(
+		db.LinkPreview.URL.Set(url),
+		db.LinkPreview.Title.SetIfPresent(title),
+		db.LinkPreview.Description.SetIfPresent(description),
+		db.LinkPreview.ImageURL.SetIfPresent(imageUrl),
+		db.LinkPreview.SiteName.SetIfPresent(siteName),
+		db.LinkPreview.Failed.Set(failed),
+	).Update(
+		db.LinkPreview.Title.SetOptional(title),
+		db.LinkPreview.Description.SetOptional(description),
+		db.LinkPreview.ImageURL.SetOptional(imageUrl),
+		db.LinkPreview.SiteName.SetOptional(siteName),
+		db.LinkPreview.Failed.Set(failed),
+		db.LinkPreview.FetchedAt.Set(time.Now()),
+	).Exec(ctx)
+}
+
+// AttachLinkPreview menautkan preview ke pesan
+func (r *ChatRepository) AttachLinkPreview(ctx context.Context, messageId, previewId string) error {
+	_, err := r.Client.Message.FindUnique(
+		db.Message.ID.Equals(messageId),
+	).Update(
+		db.Message.LinkPreview.Link(db.LinkPreview.ID.Equals(previewId)),
+	).Exec(ctx)
+	return err
+}
+
+// CreateLiveLocation membuat sesi lokasi terkini untuk pesan LOCATION yang baru dikirim
+func (r *ChatRepository) CreateLiveLocation(ctx context.Context, messageId, chatId, userId string, latitude, longitude float64, expiresAt time.Time) (*db.LiveLocationModel, error) {
+	return r.Client.LiveLocation.CreateOne(
+		db.LiveLocation.Latitude.Set(latitude),
+		db.LiveLocation.Longitude.Set(longitude),
+		db.LiveLocation.ExpiresAt.Set(expiresAt),
+		db.LiveLocation.Message.Link(db.Message.ID.Equals(messageId)),
+		db.LiveLocation.Chat.Link(db.Chat.ID.Equals(chatId)),
+		db.LiveLocation.User.Link(db.User.ID.Equals(userId)),
+	).Exec(ctx)
+}
+
+// activeLiveLocation filter sesi yang belum dihentikan, belum kedaluwarsa, dan pesannya belum dihapus.
+// messageFilters ditambahkan ke filter pesan sesi (mis. visibleTo).
+func activeLiveLocation(now time.Time, messageFilters ...db.MessageWhereParam) []db.LiveLocationWhereParam {
+	return []db.LiveLocationWhereParam{
+		db.LiveLocation.EndedAt.IsNull(),
+		db.LiveLocation.ExpiresAt.Gt(now),
+		db.LiveLocation.Message.Where(
+			append([]db.MessageWhereParam{db.Message.IsDeleted.Equals(false)}, messageFilters...)...,
+		),
+	}
+}
+
+// GetActiveLiveLocation mengambil sesi aktif milik user di chat beserta pesannya.
+// Mengembalikan ErrNoLiveLocation jika user tidak sedang membagikan lokasi.
+func (r *ChatRepository) GetActiveLiveLocation(ctx context.Context, chatId, userId string, now time.Time) (*db.LiveLocationModel, error) {
+	where := append(activeLiveLocation(now),
+		db.LiveLocation.ChatID.Equals(chatId),
+		db.LiveLocation.UserID.Equals(userId),
+	)
+	session, err := r.Client.LiveLocation.FindFirst(where...).With(
+		db.LiveLocation.Message.Fetch(),
+	).OrderBy(
+		db.LiveLocation.StartedAt.Order(db.SortOrderDesc),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, ErrNoLiveLocation
+	}
+	return session, err
+}
+
+// GetChatLiveLocations mengambil semua sesi aktif di chat untuk peserta yang baru membuka chat.
+// Sesi dari pesan yang di-drop karena blokir hanya terlihat oleh pengirimnya.
+func (r *ChatRepository) GetChatLiveLocations(ctx context.Context, chatId, viewerId string, now time.Time) ([]db.LiveLocationModel, error) {
+	where := append(activeLiveLocation(now, visibleTo(viewerId)),
+		db.LiveLocation.ChatID.Equals(chatId),
+	)
+	return r.Client.LiveLocation.FindMany(where...).With(
+		db.LiveLocation.User.Fetch(),
+	).OrderBy(
+		db.LiveLocation.StartedAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
+// UpdateLiveLocationPoint menimpa titik terakhir sesi; riwayat pergerakan tidak disimpan
+func (r *ChatRepository) UpdateLiveLocationPoint(ctx context.Context, id string, latitude, longitude float64, accuracy, heading *float64, at time.Time) (*db.LiveLocationModel, error) {
+	return r.Client.LiveLocation.FindUnique(
+		db.LiveLocation.ID.Equals(id),
+	).Update(
+		db.LiveLocation.Latitude.Set(latitude),
+		db.LiveLocation.Longitude.Set(longitude),
+		db.LiveLocation.Accuracy.SetOptional(accuracy),
+		db.LiveLocation.Heading.SetOptional(heading),
+		db.LiveLocation.LastPointAt.Set(at),
+	).Exec(ctx)
+}
+
+// EndLiveLocation menghentikan satu sesi lokasi terkini
+func (r *ChatRepository) EndLiveLocation(ctx context.Context, id string, at time.Time) (*db.LiveLocationModel, error) {
+	return r.Client.LiveLocation.FindUnique(
+		db.LiveLocation.ID.Equals(id),
+	).Update(
+		db.LiveLocation.EndedAt.Set(at),
+	).Exec(ctx)
+}
+
+// EndActiveLiveLocations menghentikan semua sesi user yang masih berjalan di chat
+// (dipakai saat user memulai sesi baru, karena satu user hanya punya satu sesi per chat)
+func (r *ChatRepository) EndActiveLiveLocations(ctx context.Context, chatId, userId string, now time.Time) ([]db.LiveLocationModel, error) {
+	return r.endLiveLocations(ctx, now, 0,
+		db.LiveLocation.ChatID.Equals(chatId),
+		db.LiveLocation.UserID.Equals(userId),
+		db.LiveLocation.EndedAt.IsNull(),
+		db.LiveLocation.ExpiresAt.Gt(now),
+	)
+}
+
+// EndExpiredLiveLocations menghentikan sesi yang sudah melewati expiresAt, maksimal limit sesi per panggilan
+func (r *ChatRepository) EndExpiredLiveLocations(ctx context.Context, now time.Time, limit int) ([]db.LiveLocationModel, error) {
+	return r.endLiveLocations(ctx, now, limit,
+		db.LiveLocation.EndedAt.IsNull(),
+		db.LiveLocation.ExpiresAt.Lte(now),
+	)
+}
+
+// endLiveLocations mengisi endedAt = now untuk sesi yang cocok dengan filter dan mengembalikan sesi tersebut
+// (beserta pesannya, nilai sebelum diperbarui) agar peserta chat bisa dikabari. limit 0 berarti tanpa batas.
+func (r *ChatRepository) endLiveLocations(ctx context.Context, now time.Time, limit int, where ...db.LiveLocationWhereParam) ([]db.LiveLocationModel, error) {
+	query := r.Client.LiveLocation.FindMany(where...).With(
+		db.LiveLocation.Message.Fetch(),
+	).OrderBy(
+		db.LiveLocation.ExpiresAt.Order(db.SortOrderAsc),
+	)
+	if limit > 0 {
+		query = query.Take(limit)
+	}
+	sessions, err := query.Exec(ctx)
+	if err != nil || len(sessions) == 0 {
+		return nil, err
+	}
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	if _, err := r.Client.LiveLocation.FindMany(
+		db.LiveLocation.ID.In(ids),
+	).Update(
+		db.LiveLocation.EndedAt.Set(now),
+	).Exec(ctx); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// optionalString mengubah string kosong menjadi nil untuk kolom opsional
+func optionalString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
@@ -33,7 +33,8 @@ func (r *ContactRepository) AddContact(userId string, contactEmail string, alias
 		db.User.Email.Equals(contactEmail),
 	).Exec(ctx)
 
-	if err != nil {
+	// User yang memblokir kita diperlakukan seperti tidak terdaftar
+	if err != nil || isBlocked(ctx, r.client, targetUser.ID, requestingUser.ID) {
 		return nil, fmt.Errorf("User with email %s not found. Please make sure they are registered.", contactEmail)
 	}
 
@@ -99,6 +100,16 @@ func (r *ContactRepository) GetContacts(userId string) ([]models.ContactResponse
 		return nil, err
 	}
 
+	// Foto profil kontak yang memblokir kita disembunyikan
+	blockers, err := blockerIDs(ctx, r.client, userId)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[string]bool, len(blockers))
+	for _, id := range blockers {
+		hidden[id] = true
+	}
+
 	var contactResponses []models.ContactResponse
 	for _, c := range contacts {
 		targetUser := c.ContactUser() // Get relation
@@ -109,7 +120,7 @@ func (r *ContactRepository) GetContacts(userId string) ([]models.ContactResponse
 		}
 
 		avatarUrl := ""
-		if v, ok := targetUser.AvatarURL(); ok {
+		if v, ok := targetUser.AvatarURL(); ok && !hidden[targetUser.ID] {
 			avatarUrl = v
 		}
 		
@@ -131,3 +142,48 @@ func (r *ContactRepository) GetContacts(userId string) ([]models.ContactResponse
 
 	return contactResponses, nil
 }
+
+// IsContact mengecek apakah userId sudah menyimpan contactId sebagai kontak
+func (r *ContactRepository) IsContact(userId, contactId string) bool {
+	_, err := r.client.Contact.FindUnique(
+		db.Contact.UserIDContactID(
+			db.Contact.UserID.Equals(userId),
+			db.Contact.ContactID.Equals(contactId),
+		),
+	).Exec(context.Background())
+	return err == nil
+}
+
+// FilterContacts mengembalikan ID dari candidateIds yang sudah disimpan userId sebagai kontak
+func (r *ContactRepository) FilterContacts(userId string, candidateIds []string) (map[string]bool, error) {
+	contacts, err := r.client.Contact.FindMany(
+		db.Contact.UserID.Equals(userId),
+		db.Contact.ContactID.In(candidateIds),
+	).Exec(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(contacts))
+	for _, c := range contacts {
+		result[c.ContactID] = true
+	}
+	return result, nil
+}
+
+// FilterSavedBy mengembalikan ID dari userIds yang sudah menyimpan contactId sebagai kontak
+func (r *ContactRepository) FilterSavedBy(contactId string, userIds []string) (map[string]bool, error) {
+	contacts, err := r.client.Contact.FindMany(
+		db.Contact.ContactID.Equals(contactId),
+		db.Contact.UserID.In(userIds),
+	).Exec(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(contacts))
+	for _, c := range contacts {
+		result[c.UserID] = true
+	}
+	return result, nil
+}
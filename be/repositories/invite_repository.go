@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Error bisnis untuk tautan undangan grup
+var (
+	ErrInviteInvalid = errors.New("tautan undangan tidak valid atau sudah dicabut")
+	ErrInviteExpired = errors.New("tautan undangan sudah kedaluwarsa")
+	ErrInviteUsedUp  = errors.New("tautan undangan sudah mencapai batas pemakaian")
+)
+
+// InviteRepository menangani tautan undangan grup dan permintaan bergabung
+type InviteRepository struct {
+	Client *db.PrismaClient
+}
+
+// NewInviteRepository inisialisasi repo undangan
+func NewInviteRepository(client *db.PrismaClient) *InviteRepository {
+	return &InviteRepository{Client: client}
+}
+
+// CreateInvite membuat token undangan baru. expiresAt & maxUses opsional (nil = tanpa batas).
+func (r *InviteRepository) CreateInvite(ctx context.Context, chatId, userId string, expiresAt *time.Time, maxUses *int) (*db.GroupInviteModel, error) {
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Client.GroupInvite.CreateOne(
+		db.GroupInvite.Token.Set(token),
+		db.GroupInvite.Chat.Link(db.Chat.ID.Equals(chatId)),
+		db.GroupInvite.CreatedBy.Link(db.User.ID.Equals(userId)),
+		db.GroupInvite.ExpiresAt.SetIfPresent(expiresAt),
+		db.GroupInvite.MaxUses.SetIfPresent(maxUses),
+	).Exec(ctx)
+}
+
+// GetActiveInvites mengambil undangan grup yang belum dicabut
+func (r *InviteRepository) GetActiveInvites(ctx context.Context, chatId string) ([]db.GroupInviteModel, error) {
+	return r.Client.GroupInvite.FindMany(
+		db.GroupInvite.ChatID.Equals(chatId),
+		db.GroupInvite.RevokedAt.IsNull(),
+	).OrderBy(
+		db.GroupInvite.CreatedAt.Order(db.SortOrderDesc),
+	).Exec(ctx)
+}
+
+// RevokeInvite mencabut undangan sehingga token tidak bisa dipakai lagi
+func (r *InviteRepository) RevokeInvite(ctx context.Context, chatId, inviteId string) (*db.GroupInviteModel, error) {
+	invite, err := r.Client.GroupInvite.FindFirst(
+		db.GroupInvite.ID.Equals(inviteId),
+		db.GroupInvite.ChatID.Equals(chatId),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Client.GroupInvite.FindUnique(
+		db.GroupInvite.ID.Equals(invite.ID),
+	).Update(
+		db.GroupInvite.RevokedAt.Set(time.Now()),
+	).Exec(ctx)
+}
+
+// GetUsableInvite mengambil undangan berdasarkan token beserta grup & anggotanya,
+// sekaligus memastikan undangan belum dicabut, belum kedaluwarsa, dan belum habis kuotanya.
+func (r *InviteRepository) GetUsableInvite(ctx context.Context, token string) (*db.GroupInviteModel, error) {
+	invite, err := r.Client.GroupInvite.FindUnique(
+		db.GroupInvite.Token.Equals(token),
+	).With(
+		db.GroupInvite.Chat.Fetch().With(
+			db.Chat.Participants.Fetch().With(
+				db.Participant.User.Fetch(),
+			).OrderBy(
+				db.Participant.JoinedAt.Order(db.SortOrderAsc),
+			),
+		),
+	).Exec(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrInviteInvalid
+		}
+		return nil, err
+	}
+
+	if err := checkUsable(invite, time.Now()); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// ClaimUse mencatat satu pemakaian undangan secara atomik, sebelum user benar-benar dimasukkan.
+// Syarat (belum dicabut, belum kedaluwarsa, kuota tersisa) dicek di UPDATE yang sama, sehingga
+// join bersamaan tidak bisa melewati maxUses.
+func (r *InviteRepository) ClaimUse(ctx context.Context, inviteId string) error {
+	res, err := r.Client.Prisma.ExecuteRaw(`
+		UPDATE "GroupInvite"
+		SET "useCount" = "useCount" + 1
+		WHERE "id" = $1
+		  AND "revokedAt" IS NULL
+		  AND ("expiresAt" IS NULL OR "expiresAt" > NOW())
+		  AND ("maxUses" IS NULL OR "useCount" < "maxUses")`, inviteId).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if res.Count > 0 {
+		return nil
+	}
+
+	// Tidak ada baris yang berubah: cari tahu alasannya untuk pesan error
+	invite, err := r.Client.GroupInvite.FindUnique(
+		db.GroupInvite.ID.Equals(inviteId),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return ErrInviteInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkUsable(invite, time.Now()); err != nil {
+		return err
+	}
+	return ErrInviteUsedUp
+}
+
+// ReleaseUse mengembalikan pemakaian yang sudah diklaim jika user gagal dimasukkan ke grup
+func (r *InviteRepository) ReleaseUse(ctx context.Context, inviteId string) error {
+	_, err := r.Client.GroupInvite.FindMany(
+		db.GroupInvite.ID.Equals(inviteId),
+		db.GroupInvite.UseCount.Gt(0),
+	).Update(
+		db.GroupInvite.UseCount.Decrement(1),
+	).Exec(ctx)
+	return err
+}
+
+// checkUsable memastikan undangan belum dicabut, belum kedaluwarsa, dan belum habis kuotanya
+func checkUsable(invite *db.GroupInviteModel, now time.Time) error {
+	if _, revoked := invite.RevokedAt(); revoked {
+		return ErrInviteInvalid
+	}
+	if exp, ok := invite.ExpiresAt(); ok && now.After(exp) {
+		return ErrInviteExpired
+	}
+	if limit, ok := invite.MaxUses(); ok && invite.UseCount >= limit {
+		return ErrInviteUsedUp
+	}
+	return nil
+}
+
+// CreateJoinRequest membuat permintaan bergabung. Jika sudah ada yang PENDING, itu yang dikembalikan.
+func (r *InviteRepository) CreateJoinRequest(ctx context.Context, chatId, userId, inviteId string) (*db.GroupJoinRequestModel, error) {
+	existing, err := r.Client.GroupJoinRequest.FindFirst(
+		db.GroupJoinRequest.ChatID.Equals(chatId),
+		db.GroupJoinRequest.UserID.Equals(userId),
+		db.GroupJoinRequest.Status.Equals(db.JoinRequestStatusPending),
+	).Exec(ctx)
+	if err == nil && existing != nil {
+		return existing, nil
+	}
+
+	return r.Client.GroupJoinRequest.CreateOne(
+		db.GroupJoinRequest.Chat.Link(db.Chat.ID.Equals(chatId)),
+		db.GroupJoinRequest.User.Link(db.User.ID.Equals(userId)),
+		db.GroupJoinRequest.Invite.Link(db.GroupInvite.ID.Equals(inviteId)),
+	).Exec(ctx)
+}
+
+// GetPendingJoinRequests mengambil permintaan bergabung yang menunggu persetujuan
+func (r *InviteRepository) GetPendingJoinRequests(ctx context.Context, chatId string) ([]db.GroupJoinRequestModel, error) {
+	return r.Client.GroupJoinRequest.FindMany(
+		db.GroupJoinRequest.ChatID.Equals(chatId),
+		db.GroupJoinRequest.Status.Equals(db.JoinRequestStatusPending),
+	).With(
+		db.GroupJoinRequest.User.Fetch(),
+	).OrderBy(
+		db.GroupJoinRequest.CreatedAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
+// GetPendingJoinRequest mengambil satu permintaan PENDING di grup tertentu
+func (r *InviteRepository) GetPendingJoinRequest(ctx context.Context, chatId, requestId string) (*db.GroupJoinRequestModel, error) {
+	return r.Client.GroupJoinRequest.FindFirst(
+		db.GroupJoinRequest.ID.Equals(requestId),
+		db.GroupJoinRequest.ChatID.Equals(chatId),
+		db.GroupJoinRequest.Status.Equals(db.JoinRequestStatusPending),
+	).With(
+		db.GroupJoinRequest.User.Fetch(),
+	).Exec(ctx)
+}
+
+// DecideJoinRequest menyimpan keputusan admin (APPROVED / REJECTED)
+func (r *InviteRepository) DecideJoinRequest(ctx context.Context, requestId string, status db.JoinRequestStatus) (*db.GroupJoinRequestModel, error) {
+	return r.Client.GroupJoinRequest.FindUnique(
+		db.GroupJoinRequest.ID.Equals(requestId),
+	).Update(
+		db.GroupJoinRequest.Status.Set(status),
+		db.GroupJoinRequest.DecidedAt.Set(time.Now()),
+	).Exec(ctx)
+}
+
+// generateInviteToken membuat token acak yang aman untuk dibagikan di URL
+func generateInviteToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"context"
+	"errors"
+)
+
+// MediaRepository mencatat file yang diunggah user beserta pesan yang memilikinya
+type MediaRepository struct {
+	Client *db.PrismaClient
+}
+
+// NewMediaRepository inisialisasi repo media
+func NewMediaRepository(client *db.PrismaClient) *MediaRepository {
+	return &MediaRepository{Client: client}
+}
+
+// Record mencatat file hasil upload beserta pemiliknya
+func (r *MediaRepository) Record(ctx context.Context, ownerId, url, publicId, resourceType string) (*db.MediaUploadModel, error) {
+	return r.Client.MediaUpload.CreateOne(
+		db.MediaUpload.URL.Set(url),
+		db.MediaUpload.PublicID.Set(publicId),
+		db.MediaUpload.ResourceType.Set(resourceType),
+		db.MediaUpload.Owner.Link(db.User.ID.Equals(ownerId)),
+	).Exec(ctx)
+}
+
+// AttachToMessage menautkan upload milik pengirim ke pesan media yang memakai URL-nya.
+// Hanya upload yang belum dipakai pesan lain yang ditautkan, sehingga salinan (forward,
+// broadcast) tidak pernah ikut memiliki file. URL yang tidak tercatat diabaikan.
+func (r *MediaRepository) AttachToMessage(ctx context.Context, messageId, senderId, url string) error {
+	upload, err := r.Client.MediaUpload.FindFirst(
+		db.MediaUpload.URL.Equals(url),
+		db.MediaUpload.OwnerID.Equals(senderId),
+		db.MediaUpload.MessageID.IsNull(),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Client.MediaUpload.FindUnique(
+		db.MediaUpload.ID.Equals(upload.ID),
+	).Update(
+		db.MediaUpload.Message.Link(db.Message.ID.Equals(messageId)),
+	).Exec(ctx)
+	return err
+}
+
+// Delete menghapus catatan upload (setelah file-nya dihapus dari Cloudinary)
+func (r *MediaRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.Client.MediaUpload.FindUnique(
+		db.MediaUpload.ID.Equals(id),
+	).Delete().Exec(ctx)
+	return err
+}
+
+// IsReferenced mengecek apakah URL media masih dipakai di tempat lain: pesan lain (mis. hasil
+// forward), foto profil, status, atau ikon grup
+func (r *MediaRepository) IsReferenced(ctx context.Context, url string) bool {
+	if _, err := r.Client.Message.FindFirst(db.Message.Content.Equals(url)).Exec(ctx); err == nil {
+		return true
+	}
+	if _, err := r.Client.User.FindFirst(db.User.AvatarURL.Equals(url)).Exec(ctx); err == nil {
+		return true
+	}
+	if _, err := r.Client.Status.FindFirst(db.Status.MediaURL.Equals(url)).Exec(ctx); err == nil {
+		return true
+	}
+	_, err := r.Client.Chat.FindFirst(db.Chat.Icon.Equals(url)).Exec(ctx)
+	return err == nil
+}
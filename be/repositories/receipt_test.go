@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"testing"
+	"time"
+)
+
+var receiptBase = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// minuteAt mengembalikan waktu receiptBase + n menit
+func minuteAt(n int) time.Time {
+	return receiptBase.Add(time.Duration(n) * time.Minute)
+}
+
+func testMessage(senderID string, minute int) db.MessageModel {
+	return db.MessageModel{InnerMessage: db.InnerMessage{
+		ID:        "m" + senderID,
+		SenderID:  senderID,
+		Timestamp: minuteAt(minute),
+	}}
+}
+
+// testParticipant membuat anggota dengan cursor baca / terima opsional (menit < 0 = tanpa cursor)
+func testParticipant(userID string, joinedMinute, readMinute, deliveredMinute int) db.ParticipantModel {
+	p := db.ParticipantModel{InnerParticipant: db.InnerParticipant{
+		UserID:   userID,
+		JoinedAt: minuteAt(joinedMinute),
+	}}
+	if readMinute >= 0 {
+		read := testMessage("x", readMinute)
+		p.RelationsParticipant.LastReadMessage = &read
+	}
+	if deliveredMinute >= 0 {
+		delivered := testMessage("x", deliveredMinute)
+		p.RelationsParticipant.LastDeliveredMessage = &delivered
+	}
+	return p
+}
+
+func TestCoveredByAllRead(t *testing.T) {
+	msg := testMessage("alice", 5)
+
+	tests := []struct {
+		name         string
+		participants []db.ParticipantModel
+		want         bool
+	}{
+		{
+			name: "semua penerima sudah membaca",
+			participants: []db.ParticipantModel{
+				testParticipant("alice", 0, -1, -1),
+				testParticipant("bob", 0, 5, -1),
+				testParticipant("carol", 0, 7, -1),
+			},
+			want: true,
+		},
+		{
+			name: "satu penerima cursornya masih sebelum pesan",
+			participants: []db.ParticipantModel{
+				testParticipant("bob", 0, 5, -1),
+				testParticipant("carol", 0, 4, -1),
+			},
+			want: false,
+		},
+		{
+			name: "penerima tanpa cursor baca menahan status",
+			participants: []db.ParticipantModel{
+				testParticipant("bob", 0, 5, -1),
+				testParticipant("carol", 1, -1, -1),
+			},
+			want: false,
+		},
+		{
+			name: "anggota yang bergabung setelah pesan diabaikan",
+			participants: []db.ParticipantModel{
+				testParticipant("bob", 0, 6, -1),
+				testParticipant("dave", 10, -1, -1),
+			},
+			want: true,
+		},
+		{
+			name: "cursor pengirim sendiri tidak dihitung",
+			participants: []db.ParticipantModel{
+				testParticipant("alice", 0, -1, -1),
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coveredByAll(msg, tt.participants, readCursor); got != tt.want {
+				t.Errorf("coveredByAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoveredByAllDelivered(t *testing.T) {
+	msg := testMessage("alice", 5)
+
+	// Pesan yang sudah dibaca otomatis dianggap sudah sampai walau cursor terima tertinggal
+	participants := []db.ParticipantModel{
+		testParticipant("bob", 0, 6, 2),
+		testParticipant("carol", 0, -1, 5),
+	}
+	if !coveredByAll(msg, participants, deliveredCursor) {
+		t.Error("pesan seharusnya DELIVERED ke semua penerima")
+	}
+
+	participants = append(participants, testParticipant("dave", 0, -1, 3))
+	if coveredByAll(msg, participants, deliveredCursor) {
+		t.Error("pesan belum sampai ke dave, seharusnya belum DELIVERED")
+	}
+}
+
+func TestDeliveredCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		p      db.ParticipantModel
+		want   time.Time
+		wantOk bool
+	}{
+		{"tanpa cursor", testParticipant("bob", 0, -1, -1), time.Time{}, false},
+		{"hanya cursor baca", testParticipant("bob", 0, 4, -1), minuteAt(4), true},
+		{"cursor terima lebih baru", testParticipant("bob", 0, 4, 8), minuteAt(8), true},
+		{"cursor baca lebih baru", testParticipant("bob", 0, 9, 8), minuteAt(9), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := deliveredCursor(tt.p)
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("deliveredCursor() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrReportDuplicate dipakai saat user melaporkan konten yang sama dan laporannya masih ditinjau
+var ErrReportDuplicate = errors.New("Anda sudah melaporkan konten ini dan laporannya masih ditinjau")
+
+// ErrReportTargetNotFound dipakai saat konten yang dilaporkan tidak ada atau tidak bisa diakses pelapor
+var ErrReportTargetNotFound = errors.New("konten yang dilaporkan tidak ditemukan")
+
+// ErrReportOwnContent dipakai saat user melaporkan konten / akunnya sendiri
+var ErrReportOwnContent = errors.New("tidak dapat melaporkan konten sendiri")
+
+// ErrReportClosed dipakai saat laporan yang sudah diselesaikan / ditolak mau diproses lagi
+var ErrReportClosed = errors.New("laporan sudah ditutup")
+
+// ErrModerationActionNotApplicable dipakai saat tindakan moderator tidak cocok dengan jenis laporan
+var ErrModerationActionNotApplicable = errors.New("tindakan tidak berlaku untuk laporan ini")
+
+// ErrSuspendDurationRequired dipakai saat tindakan SUSPEND dikirim tanpa durasi
+var ErrSuspendDurationRequired = errors.New("durasi penangguhan (durationHours) wajib diisi")
+
+// openReportStatuses status laporan yang masih perlu ditangani moderator
+var openReportStatuses = []db.ReportStatus{db.ReportStatusOpen, db.ReportStatusInReview}
+
+// ReportRepository menangani laporan penyalahgunaan dan tindakan moderator
+type ReportRepository struct {
+	Client *db.PrismaClient
+}
+
+// NewReportRepository inisialisasi repo laporan & moderasi
+func NewReportRepository(client *db.PrismaClient) *ReportRepository {
+	return &ReportRepository{Client: client}
+}
+
+// Create menyimpan laporan baru beserta snapshot kontennya (JSON).
+// extras dipakai untuk field opsional (pemilik konten, chat asal, detail alasan).
+// Laporan ganda dari pelapor yang sama untuk target yang masih ditinjau ditolak.
+func (r *ReportRepository) Create(ctx context.Context, reporterId string, targetType db.ReportTargetType, targetId string, reason db.ReportReason, snapshot []byte, extras ...db.ReportSetParam) (*db.ReportModel, error) {
+	existing, err := r.Client.Report.FindFirst(
+		db.Report.ReporterID.Equals(reporterId),
+		db.Report.TargetType.Equals(targetType),
+		db.Report.TargetID.Equals(targetId),
+		db.Report.Status.In(openReportStatuses),
+	).Exec(ctx)
+	if err == nil && existing != nil {
+		return nil, ErrReportDuplicate
+	}
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	params := append([]db.ReportSetParam{
+		db.Report.Reporter.Link(db.User.ID.Equals(reporterId)),
+	}, extras...)
+	return r.Client.Report.CreateOne(
+		db.Report.TargetType.Set(targetType),
+		db.Report.TargetID.Set(targetId),
+		db.Report.Reason.Set(reason),
+		db.Report.Snapshot.Set(db.JSON(snapshot)),
+		params...,
+	).Exec(ctx)
+}
+
+// GetQueue mengambil antrean laporan sesuai filter status & jenis target, terlama dulu
+// supaya laporan yang paling lama menunggu ditangani lebih dulu.
+func (r *ReportRepository) GetQueue(ctx context.Context, statuses []db.ReportStatus, targetType string, skip, take int) ([]db.ReportModel, error) {
+	where := []db.ReportWhereParam{
+		db.Report.Status.In(statuses),
+	}
+	if targetType != "" {
+		where = append(where, db.Report.TargetType.Equals(db.ReportTargetType(targetType)))
+	}
+
+	return r.Client.Report.FindMany(where...).With(
+		db.Report.Reporter.Fetch(),
+		db.Report.ReportedUser.Fetch(),
+		db.Report.Assignee.Fetch(),
+	).OrderBy(
+		db.Report.CreatedAt.Order(db.SortOrderAsc),
+	).Skip(skip).Take(take).Exec(ctx)
+}
+
+// GetByID mengambil satu laporan lengkap dengan riwayat tindakan moderator
+func (r *ReportRepository) GetByID(ctx context.Context, id string) (*db.ReportModel, error) {
+	return r.Client.Report.FindUnique(
+		db.Report.ID.Equals(id),
+	).With(
+		db.Report.Reporter.Fetch(),
+		db.Report.ReportedUser.Fetch(),
+		db.Report.Assignee.Fetch(),
+		db.Report.Actions.Fetch().With(
+			db.ModerationAction.Moderator.Fetch(),
+		).OrderBy(
+			db.ModerationAction.CreatedAt.Order(db.SortOrderAsc),
+		),
+	).Exec(ctx)
+}
+
+// CountOpenAgainst menghitung laporan lain yang masih terbuka terhadap user yang sama
+// (membantu moderator menilai pelanggar berulang)
+func (r *ReportRepository) CountOpenAgainst(ctx context.Context, userId string) (int, error) {
+	var rows []struct {
+		Count db.RawInt `json:"count"`
+	}
+	err := r.Client.Prisma.QueryRaw(`
+		SELECT COUNT(*)::int AS "count"
+		FROM "Report"
+		WHERE "reportedUserId" = $1 AND "status" IN ('OPEN', 'IN_REVIEW')`, userId).Exec(ctx, &rows)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return int(rows[0].Count), nil
+}
+
+// Claim menandai laporan sedang ditinjau oleh moderator (boleh mengambil alih dari moderator lain).
+// Laporan yang sudah ditutup ditolak dengan ErrReportClosed.
+func (r *ReportRepository) Claim(ctx context.Context, id, moderatorId string) (*db.ReportModel, error) {
+	report, err := r.Client.Report.FindUnique(
+		db.Report.ID.Equals(id),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenReport(report.Status) {
+		return nil, ErrReportClosed
+	}
+
+	return r.Client.Report.FindUnique(
+		db.Report.ID.Equals(id),
+	).Update(
+		db.Report.Status.Set(db.ReportStatusInReview),
+		db.Report.Assignee.Link(db.User.ID.Equals(moderatorId)),
+	).Exec(ctx)
+}
+
+// AddAction mencatat tindakan moderator pada laporan
+func (r *ReportRepository) AddAction(ctx context.Context, reportId, moderatorId string, actionType db.ModerationActionType, note string, suspendedUntil *time.Time) (*db.ModerationActionModel, error) {
+	params := []db.ModerationActionSetParam{
+		db.ModerationAction.Moderator.Link(db.User.ID.Equals(moderatorId)),
+	}
+	if note != "" {
+		params = append(params, db.ModerationAction.Note.Set(note))
+	}
+	if suspendedUntil != nil {
+		params = append(params, db.ModerationAction.SuspendedUntil.Set(*suspendedUntil))
+	}
+
+	return r.Client.ModerationAction.CreateOne(
+		db.ModerationAction.Type.Set(actionType),
+		db.ModerationAction.Report.Link(db.Report.ID.Equals(reportId)),
+		params...,
+	).Exec(ctx)
+}
+
+// Resolve menutup laporan (RESOLVED / DISMISSED) beserta catatan penyelesaiannya
+func (r *ReportRepository) Resolve(ctx context.Context, id, moderatorId string, status db.ReportStatus, resolution string) (*db.ReportModel, error) {
+	report, err := r.Client.Report.FindUnique(
+		db.Report.ID.Equals(id),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenReport(report.Status) {
+		return nil, ErrReportClosed
+	}
+
+	return r.Client.Report.FindUnique(
+		db.Report.ID.Equals(id),
+	).Update(
+		db.Report.Status.Set(status),
+		db.Report.Resolution.Set(resolution),
+		db.Report.ResolvedAt.Set(time.Now()),
+		db.Report.Assignee.Link(db.User.ID.Equals(moderatorId)),
+	).Exec(ctx)
+}
+
+// isOpenReport mengecek apakah laporan masih bisa diproses moderator
+func isOpenReport(status db.ReportStatus) bool {
+	for _, s := range openReportStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrScheduledNotPending dipakai saat pesan terjadwal sudah terkirim/dibatalkan sehingga tidak bisa diubah
+var ErrScheduledNotPending = errors.New("pesan terjadwal sudah diproses atau dibatalkan")
+
+// ScheduledMessageRepository menangani pesan yang dijadwalkan untuk dikirim nanti
+type ScheduledMessageRepository struct {
+	Client *db.PrismaClient
+}
+
+// NewScheduledMessageRepository inisialisasi repo pesan terjadwal
+func NewScheduledMessageRepository(client *db.PrismaClient) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{Client: client}
+}
+
+// Create menyimpan pesan terjadwal baru
+func (r *ScheduledMessageRepository) Create(ctx context.Context, chatId, senderId, content string, msgType db.MessageType, replyToId string, sendAt time.Time) (*db.ScheduledMessageModel, error) {
+	var replyTo *string
+	if replyToId != "" {
+		replyTo = &replyToId
+	}
+
+	return r.Client.ScheduledMessage.CreateOne(
+		db.ScheduledMessage.Content.Set(content),
+		db.ScheduledMessage.SendAt.Set(sendAt),
+		db.ScheduledMessage.Chat.Link(db.Chat.ID.Equals(chatId)),
+		db.ScheduledMessage.Sender.Link(db.User.ID.Equals(senderId)),
+		db.ScheduledMessage.Type.Set(msgType),
+		db.ScheduledMessage.ReplyToID.SetIfPresent(replyTo),
+	).Exec(ctx)
+}
+
+// GetPending mengambil pesan terjadwal milik user di satu chat yang belum dikirim
+func (r *ScheduledMessageRepository) GetPending(ctx context.Context, chatId, senderId string) ([]db.ScheduledMessageModel, error) {
+	return r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.ChatID.Equals(chatId),
+		db.ScheduledMessage.SenderID.Equals(senderId),
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusPending),
+	).OrderBy(
+		db.ScheduledMessage.SendAt.Order(db.SortOrderAsc),
+	).Exec(ctx)
+}
+
+// GetOwnedPending mengambil satu pesan terjadwal PENDING milik user di chat tertentu
+func (r *ScheduledMessageRepository) GetOwnedPending(ctx context.Context, id, chatId, senderId string) (*db.ScheduledMessageModel, error) {
+	scheduled, err := r.Client.ScheduledMessage.FindFirst(
+		db.ScheduledMessage.ID.Equals(id),
+		db.ScheduledMessage.ChatID.Equals(chatId),
+		db.ScheduledMessage.SenderID.Equals(senderId),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.Status != db.ScheduledMessageStatusPending {
+		return nil, ErrScheduledNotPending
+	}
+	return scheduled, nil
+}
+
+// Update mengubah isi dan/atau waktu kirim pesan terjadwal yang masih PENDING (field nil tidak diubah)
+func (r *ScheduledMessageRepository) Update(ctx context.Context, id string, content *string, sendAt *time.Time) (*db.ScheduledMessageModel, error) {
+	result, err := r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.ID.Equals(id),
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusPending),
+	).Update(
+		db.ScheduledMessage.Content.SetIfPresent(content),
+		db.ScheduledMessage.SendAt.SetIfPresent(sendAt),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Scheduler sudah mengambilnya lebih dulu
+	if result.Count == 0 {
+		return nil, ErrScheduledNotPending
+	}
+
+	return r.Client.ScheduledMessage.FindUnique(
+		db.ScheduledMessage.ID.Equals(id),
+	).Exec(ctx)
+}
+
+// Cancel membatalkan pesan terjadwal yang masih PENDING
+func (r *ScheduledMessageRepository) Cancel(ctx context.Context, id string) error {
+	result, err := r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.ID.Equals(id),
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusPending),
+	).Update(
+		db.ScheduledMessage.Status.Set(db.ScheduledMessageStatusCanceled),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if result.Count == 0 {
+		return ErrScheduledNotPending
+	}
+	return nil
+}
+
+// GetDue mengambil pesan PENDING yang waktunya sudah tiba (terlama dulu)
+func (r *ScheduledMessageRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]db.ScheduledMessageModel, error) {
+	return r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusPending),
+		db.ScheduledMessage.SendAt.Lte(now),
+	).OrderBy(
+		db.ScheduledMessage.SendAt.Order(db.SortOrderAsc),
+	).Take(limit).Exec(ctx)
+}
+
+// Claim menandai pesan PENDING menjadi SENDING secara atomik.
+// false berarti pesan sudah diubah/dibatalkan/diambil proses lain.
+func (r *ScheduledMessageRepository) Claim(ctx context.Context, id string) (bool, error) {
+	result, err := r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.ID.Equals(id),
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusPending),
+	).Update(
+		db.ScheduledMessage.Status.Set(db.ScheduledMessageStatusSending),
+	).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	return result.Count == 1, nil
+}
+
+// MarkSent mencatat pesan yang berhasil dikirim beserta ID pesan hasilnya
+func (r *ScheduledMessageRepository) MarkSent(ctx context.Context, id, messageId string) error {
+	_, err := r.Client.ScheduledMessage.FindUnique(
+		db.ScheduledMessage.ID.Equals(id),
+	).Update(
+		db.ScheduledMessage.Status.Set(db.ScheduledMessageStatusSent),
+		db.ScheduledMessage.MessageID.Set(messageId),
+	).Exec(ctx)
+	return err
+}
+
+// MarkFailed mencatat pesan yang gagal dikirim beserta alasannya
+func (r *ScheduledMessageRepository) MarkFailed(ctx context.Context, id, reason string) error {
+	_, err := r.Client.ScheduledMessage.FindUnique(
+		db.ScheduledMessage.ID.Equals(id),
+	).Update(
+		db.ScheduledMessage.Status.Set(db.ScheduledMessageStatusFailed),
+		db.ScheduledMessage.FailureReason.Set(reason),
+	).Exec(ctx)
+	return err
+}
+
+// RecoverInterrupted memulihkan pesan SENDING yang tertahan karena server mati di tengah pengiriman.
+// Pesan yang ternyata sudah tersimpan (server mati sebelum MarkSent) ditandai SENT agar tidak terkirim
+// dua kali; sisanya dikembalikan ke PENDING. Dipanggil sekali saat scheduler mulai.
+func (r *ScheduledMessageRepository) RecoverInterrupted(ctx context.Context) error {
+	interrupted, err := r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusSending),
+		db.ScheduledMessage.MessageID.IsNull(),
+	).Exec(ctx)
+	if err != nil || len(interrupted) == 0 {
+		return err
+	}
+
+	ids := make([]string, len(interrupted))
+	for i, s := range interrupted {
+		ids[i] = s.ID
+	}
+	created, err := r.Client.Message.FindMany(
+		db.Message.ScheduledMessageID.In(ids),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	resend, sent := splitInterrupted(interrupted, created)
+	for id, messageId := range sent {
+		if err := r.MarkSent(ctx, id, messageId); err != nil {
+			return err
+		}
+	}
+	if len(resend) == 0 {
+		return nil
+	}
+	_, err = r.Client.ScheduledMessage.FindMany(
+		db.ScheduledMessage.ID.In(resend),
+		db.ScheduledMessage.Status.Equals(db.ScheduledMessageStatusSending),
+	).Update(
+		db.ScheduledMessage.Status.Set(db.ScheduledMessageStatusPending),
+	).Exec(ctx)
+	return err
+}
+
+// splitInterrupted memisahkan pesan terjadwal yang tertahan menjadi yang perlu dikirim ulang dan
+// yang pesannya sudah tersimpan (ID jadwal -> ID pesan)
+func splitInterrupted(interrupted []db.ScheduledMessageModel, created []db.MessageModel) ([]string, map[string]string) {
+	sent := make(map[string]string)
+	for _, m := range created {
+		if scheduledId, ok := m.ScheduledMessageID(); ok {
+			sent[scheduledId] = m.ID
+		}
+	}
+
+	var resend []string
+	for _, s := range interrupted {
+		if _, ok := sent[s.ID]; !ok {
+			resend = append(resend, s.ID)
+		}
+	}
+	return resend, sent
+}
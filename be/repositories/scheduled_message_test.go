@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"chat-app-be/prisma/db"
+	"reflect"
+	"testing"
+)
+
+func testScheduled(id string) db.ScheduledMessageModel {
+	return db.ScheduledMessageModel{InnerScheduledMessage: db.InnerScheduledMessage{
+		ID:     id,
+		Status: db.ScheduledMessageStatusSending,
+	}}
+}
+
+// testScheduledMessage membuat pesan hasil kirim terjadwal (scheduledID kosong = pesan biasa)
+func testScheduledMessage(id, scheduledID string) db.MessageModel {
+	m := db.MessageModel{InnerMessage: db.InnerMessage{ID: id}}
+	if scheduledID != "" {
+		m.InnerMessage.ScheduledMessageID = &scheduledID
+	}
+	return m
+}
+
+func TestSplitInterrupted(t *testing.T) {
+	tests := []struct {
+		name        string
+		interrupted []db.ScheduledMessageModel
+		created     []db.MessageModel
+		wantResend  []string
+		wantSent    map[string]string
+	}{
+		{
+			name:        "belum ada pesan tersimpan, semua dikirim ulang",
+			interrupted: []db.ScheduledMessageModel{testScheduled("s1"), testScheduled("s2")},
+			wantResend:  []string{"s1", "s2"},
+			wantSent:    map[string]string{},
+		},
+		{
+			name:        "server mati setelah CreateMessage sebelum MarkSent",
+			interrupted: []db.ScheduledMessageModel{testScheduled("s1")},
+			created:     []db.MessageModel{testScheduledMessage("m1", "s1")},
+			wantSent:    map[string]string{"s1": "m1"},
+		},
+		{
+			name:        "campuran terkirim dan belum",
+			interrupted: []db.ScheduledMessageModel{testScheduled("s1"), testScheduled("s2"), testScheduled("s3")},
+			created: []db.MessageModel{
+				testScheduledMessage("m2", "s2"),
+				testScheduledMessage("m9", ""),
+			},
+			wantResend: []string{"s1", "s3"},
+			wantSent:   map[string]string{"s2": "m2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resend, sent := splitInterrupted(tt.interrupted, tt.created)
+			if !reflect.DeepEqual(resend, tt.wantResend) {
+				t.Errorf("resend = %v, want %v", resend, tt.wantResend)
+			}
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+		})
+	}
+}
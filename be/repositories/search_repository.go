@@ -3,6 +3,9 @@ package repositories
 import (
 	"chat-app-be/prisma/db"
 	"context"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type SearchRepository struct {
@@ -45,6 +48,7 @@ func (r *SearchRepository) GlobalSearch(ctx context.Context, query string) (map[
 				db.Message.Type.Equals(db.MessageType("IMAGE")),
 				db.Message.Type.Equals(db.MessageType("VIDEO")),
 				db.Message.Type.Equals(db.MessageType("DOCUMENT")),
+				db.Message.Type.Equals(db.MessageType("AUDIO")),
 			),
 		),
 	).Take(10).With(
@@ -60,3 +64,95 @@ func (r *SearchRepository) GlobalSearch(ctx context.Context, query string) (map[
 		"files":    files,
 	}, nil
 }
+
+// searchVectorSQL adalah ekspresi tsvector untuk isi pesan. Harus sama persis dengan
+// ekspresi di index GIN supaya PostgreSQL memakai index tersebut. Pesan LOCATION / CONTACT
+// ikut tercari karena content-nya berisi teks fallback dari payload (label, alamat, nama, nomor).
+// Konfigurasi 'simple' dipakai karena pesan bercampur bahasa (tanpa stemming).
+const searchVectorSQL = `to_tsvector('simple', m."content")`
+
+// Penanda awal/akhir kata yang cocok pada snippet. Memakai karakter private-use Unicode
+// supaya controller bisa meng-escape isi pesan dulu sebelum menggantinya dengan tag <mark>.
+const (
+	HighlightStart = "\uE000"
+	HighlightStop  = "\uE001"
+)
+
+// EnsureSearchIndex membuat index GIN full-text untuk isi pesan jika belum ada.
+// Index berbasis ekspresi belum bisa dideklarasikan di schema Prisma, jadi dibuat saat aplikasi start.
+func (r *SearchRepository) EnsureSearchIndex(ctx context.Context) error {
+	_, err := r.Client.Prisma.ExecuteRaw(
+		`CREATE INDEX IF NOT EXISTS "Message_content_fts_idx" ON "Message" USING GIN (to_tsvector('simple', "content"))`,
+	).Exec(ctx)
+	return err
+}
+
+// ChatSearchFilter adalah filter pencarian pesan di dalam satu chat
+type ChatSearchFilter struct {
+	ChatID   string
+	ViewerID string // Pesan yang di-drop karena blokir hanya dicari untuk pengirimnya
+	Query    string
+	SenderID string     // Opsional
+	Type     string     // Opsional: TEXT, IMAGE, VIDEO, DOCUMENT, POLL, LOCATION, CONTACT, AUDIO
+	From     *time.Time // Opsional: batas bawah waktu pesan
+	To       *time.Time // Opsional: batas atas waktu pesan
+	Skip     int
+	Take     int
+}
+
+// ChatSearchHit adalah satu hasil pencarian: ID pesan, skor relevansi, dan potongan teks yang cocok
+type ChatSearchHit struct {
+	ID      db.RawString `json:"id"`
+	Rank    db.RawFloat  `json:"rank"`
+	Snippet db.RawString `json:"snippet"`
+}
+
+// SearchChatMessages mencari pesan di satu chat memakai full-text search PostgreSQL,
+// diurutkan berdasarkan relevansi lalu waktu. Pesan terhapus, pesan sementara yang sudah
+// kedaluwarsa, dan pesan INFO tidak ikut dicari.
+func (r *SearchRepository) SearchChatMessages(ctx context.Context, f ChatSearchFilter) ([]ChatSearchHit, error) {
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=25, MinWords=8, MaxFragments=2", HighlightStart, HighlightStop)
+	args := []interface{}{f.ChatID, f.Query, headline}
+	where := []string{
+		`m."chatId" = $1`,
+		`m."isDeleted" = false`,
+		`m."type" <> 'INFO'`,
+		`(m."expiresAt" IS NULL OR m."expiresAt" > NOW())`,
+		searchVectorSQL + ` @@ q.query`,
+	}
+	filter := func(cond string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	filter(`(m."droppedByBlock" = false OR m."senderId" = $%d)`, f.ViewerID)
+	if f.SenderID != "" {
+		filter(`m."senderId" = $%d`, f.SenderID)
+	}
+	if f.Type != "" {
+		filter(`m."type"::text = $%d`, f.Type)
+	}
+	// Kolom timestamp Prisma disimpan dalam UTC tanpa zona waktu
+	if f.From != nil {
+		filter(`m."timestamp" >= $%d::timestamp`, f.From.UTC().Format("2006-01-02 15:04:05.999"))
+	}
+	if f.To != nil {
+		filter(`m."timestamp" <= $%d::timestamp`, f.To.UTC().Format("2006-01-02 15:04:05.999"))
+	}
+	args = append(args, f.Take, f.Skip)
+
+	query := fmt.Sprintf(`
+		SELECT m."id",
+			ts_rank(%s, q.query)::float8 AS "rank",
+			ts_headline('simple', m."content", q.query, $3) AS "snippet"
+		FROM "Message" m, websearch_to_tsquery('simple', $2) AS q(query)
+		WHERE %s
+		ORDER BY "rank" DESC, m."timestamp" DESC, m."id" DESC
+		LIMIT $%d OFFSET $%d`,
+		searchVectorSQL, strings.Join(where, "\n\t\t\tAND "), len(args)-1, len(args))
+
+	var hits []ChatSearchHit
+	if err := r.Client.Prisma.QueryRaw(query, args...).Exec(ctx, &hits); err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
@@ -40,6 +40,34 @@ func (r *StatusRepository) CreateStatus(ctx context.Context, userId, mediaUrl, c
 	).Exec(ctx)
 }
 
+// GetByID mengambil satu status beserta pemiliknya
+func (r *StatusRepository) GetByID(ctx context.Context, statusId string) (*db.StatusModel, error) {
+	return r.Client.Status.FindUnique(
+		db.Status.ID.Equals(statusId),
+	).With(
+		db.Status.User.Fetch(),
+	).Exec(ctx)
+}
+
+// DeleteStatus menghapus status (like & viewer ikut terhapus, balasan chat tetap ada)
+func (r *StatusRepository) DeleteStatus(ctx context.Context, statusId string) error {
+	_, err := r.Client.Status.FindUnique(
+		db.Status.ID.Equals(statusId),
+	).Delete().Exec(ctx)
+	return err
+}
+
+// IsHiddenFrom mengecek apakah status tidak boleh diakses userId karena pemiliknya memblokir userId
+func (r *StatusRepository) IsHiddenFrom(ctx context.Context, statusId, userId string) bool {
+	status, err := r.Client.Status.FindUnique(
+		db.Status.ID.Equals(statusId),
+	).Exec(ctx)
+	if err != nil {
+		return false
+	}
+	return isBlocked(ctx, r.Client, status.UserID, userId)
+}
+
 // AddView mencatat user melihat status
 func (r *StatusRepository) AddView(ctx context.Context, statusId, userId string) error {
 	_, err := r.Client.StatusViewer.CreateOne(
@@ -108,9 +136,16 @@ func (r *StatusRepository) GetActiveStatuses(ctx context.Context, userId string)
 		allowedUserIds = append(allowedUserIds, c.ContactUser().ID)
 	}
 
+	// Status milik user yang memblokir kita tidak ditampilkan
+	hiddenUserIds, err := blockerIDs(ctx, r.Client, userId)
+	if err != nil {
+		return nil, err
+	}
+
 	return r.Client.Status.FindMany(
 		db.Status.ExpiresAt.After(time.Now()),
         db.Status.UserID.In(allowedUserIds),
+		db.Status.UserID.NotIn(hiddenUserIds),
 	).With(
 		db.Status.User.Fetch(),
 		db.Status.Likes.Fetch(),
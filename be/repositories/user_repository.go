@@ -17,12 +17,13 @@ func NewUserRepository(client *db.PrismaClient) *UserRepository {
 }
 
 // CreateUser menyimpan user baru ke database
-func (r *UserRepository) CreateUser(ctx context.Context, email, name, password, color string) (*db.UserModel, error) {
+func (r *UserRepository) CreateUser(ctx context.Context, email, name, password, color string, username *string) (*db.UserModel, error) {
 	return r.Client.User.CreateOne(
 		db.User.Email.Set(email),
 		db.User.Name.Set(name),
 		db.User.Password.Set(password),
 		db.User.Color.Set(color),
+		db.User.Username.SetIfPresent(username),
 	).Exec(ctx)
 }
 
@@ -88,12 +89,115 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, email, newPassword
 	).Exec(ctx)
 }
 
-// UpdateUser memperbarui informasi dasar user (Profil)
-func (r *UserRepository) UpdateUser(ctx context.Context, userID, name, avatarUrl string) (*db.UserModel, error) {
+// UpdateUser memperbarui informasi dasar user (Profil). username nil berarti tidak diubah.
+func (r *UserRepository) UpdateUser(ctx context.Context, userID, name, avatarUrl string, username *string) (*db.UserModel, error) {
 	return r.Client.User.FindUnique(
 		db.User.ID.Equals(userID),
 	).Update(
 		db.User.Name.Set(name),
 		db.User.AvatarURL.Set(avatarUrl),
+		db.User.Username.SetIfPresent(username),
 	).Exec(ctx)
 }
+
+// FindByID mencari user berdasarkan ID
+func (r *UserRepository) FindByID(ctx context.Context, userID string) (*db.UserModel, error) {
+	return r.Client.User.FindUnique(
+		db.User.ID.Equals(userID),
+	).Exec(ctx)
+}
+
+// UpdateLastSeen mencatat waktu terakhir user terlihat online
+func (r *UserRepository) UpdateLastSeen(ctx context.Context, userID string, at time.Time) (*db.UserModel, error) {
+	return r.Client.User.FindUnique(
+		db.User.ID.Equals(userID),
+	).Update(
+		db.User.LastSeenAt.Set(at),
+	).Exec(ctx)
+}
+
+// SuspendUser menangguhkan akun sampai waktu tertentu dan mencabut semua refresh token-nya
+func (r *UserRepository) SuspendUser(ctx context.Context, userID string, until time.Time) (*db.UserModel, error) {
+	user, err := r.Client.User.FindUnique(
+		db.User.ID.Equals(userID),
+	).Update(
+		db.User.SuspendedUntil.Set(until),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.Client.RefreshToken.FindMany(
+		db.RefreshToken.UserID.Equals(userID),
+	).Delete().Exec(ctx)
+	return user, err
+}
+
+// ActiveSuspension mengembalikan batas waktu penangguhan akun jika masih berlaku
+func ActiveSuspension(user *db.UserModel) (time.Time, bool) {
+	until, ok := user.SuspendedUntil()
+	if !ok || !until.After(time.Now()) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// BlockUser memblokir user lain (idempotent)
+func (r *UserRepository) BlockUser(ctx context.Context, blockerID, blockedID string) (*db.BlockModel, error) {
+	return r.Client.Block.UpsertOne(
+		db.Block.BlockerIDBlockedID(
+			db.Block.BlockerID.Equals(blockerID),
+			db.Block.BlockedID.Equals(blockedID),
+		),
+	).Create(
+		db.Block.Blocker.Link(db.User.ID.Equals(blockerID)),
+		db.Block.Blocked.Link(db.User.ID.Equals(blockedID)),
+	).Update().Exec(ctx)
+}
+
+// UnblockUser membuka blokir user (tidak error jika memang tidak diblokir)
+func (r *UserRepository) UnblockUser(ctx context.Context, blockerID, blockedID string) error {
+	_, err := r.Client.Block.FindMany(
+		db.Block.BlockerID.Equals(blockerID),
+		db.Block.BlockedID.Equals(blockedID),
+	).Delete().Exec(ctx)
+	return err
+}
+
+// GetBlockedUsers mengambil daftar user yang diblokir (terbaru dulu)
+func (r *UserRepository) GetBlockedUsers(ctx context.Context, blockerID string) ([]db.BlockModel, error) {
+	return r.Client.Block.FindMany(
+		db.Block.BlockerID.Equals(blockerID),
+	).With(
+		db.Block.Blocked.Fetch(),
+	).OrderBy(
+		db.Block.CreatedAt.Order(db.SortOrderDesc),
+	).Exec(ctx)
+}
+
+// isBlocked mengecek apakah blockerID memblokir blockedID
+func isBlocked(ctx context.Context, client *db.PrismaClient, blockerID, blockedID string) bool {
+	_, err := client.Block.FindUnique(
+		db.Block.BlockerIDBlockedID(
+			db.Block.BlockerID.Equals(blockerID),
+			db.Block.BlockedID.Equals(blockedID),
+		),
+	).Exec(ctx)
+	return err == nil
+}
+
+// blockerIDs mengembalikan ID user yang memblokir userID
+func blockerIDs(ctx context.Context, client *db.PrismaClient, userID string) ([]string, error) {
+	blocks, err := client.Block.FindMany(
+		db.Block.BlockedID.Equals(userID),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(blocks))
+	for i, b := range blocks {
+		ids[i] = b.BlockerID
+	}
+	return ids, nil
+}
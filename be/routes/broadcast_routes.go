@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BroadcastRoutes untuk daftar broadcast milik user
+func BroadcastRoutes(r *gin.RouterGroup, ctrl *controllers.BroadcastController) {
+	broadcasts := r.Group("/broadcasts")
+	{
+		broadcasts.GET("/", ctrl.List)
+		broadcasts.POST("/", ctrl.Create)
+		broadcasts.GET("/:id", ctrl.Get)
+		broadcasts.PUT("/:id", ctrl.Update)
+		broadcasts.DELETE("/:id", ctrl.Delete)
+		broadcasts.POST("/:id/send", ctrl.Send)
+	}
+}
@@ -11,7 +11,19 @@ func ChatRoutes(r *gin.RouterGroup, chatCtrl *controllers.ChatController) {
 	chatGroup := r.Group("/chats")
 	{
 		chatGroup.GET("/", chatCtrl.GetUserChats)
+		chatGroup.PUT("/:id/preferences", chatCtrl.UpdatePreferences)
+		chatGroup.PUT("/:id/disappearing", chatCtrl.SetDisappearing)
+		chatGroup.PUT("/:id/draft", chatCtrl.SaveDraft)
 		chatGroup.GET("/:id/messages", chatCtrl.GetMessages)
+		chatGroup.POST("/:id/messages", chatCtrl.SendMessage)
+		chatGroup.POST("/:id/polls", chatCtrl.CreatePoll)
+		chatGroup.GET("/:id/live-locations", chatCtrl.GetLiveLocations)
+		chatGroup.POST("/:id/live-locations", chatCtrl.StartLiveLocation)
+		chatGroup.DELETE("/:id/live-locations", chatCtrl.StopLiveLocation)
+		chatGroup.GET("/:id/mentions/next", chatCtrl.GetNextMention)
+		chatGroup.GET("/:id/pins", chatCtrl.GetPins)
+		chatGroup.POST("/:id/pins", chatCtrl.PinMessage)
+		chatGroup.DELETE("/:id/pins/:messageId", chatCtrl.UnpinMessage)
 		chatGroup.POST("/groups", chatCtrl.CreateGroup)
 		chatGroup.POST("/direct", chatCtrl.CreateDirectChat)
 	}
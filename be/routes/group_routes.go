@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupRoutes untuk manajemen grup (anggota, role, izin, keluar grup, info grup)
+func GroupRoutes(r *gin.RouterGroup, ctrl *controllers.GroupController) {
+	group := r.Group("/chats")
+	{
+		group.PUT("/:id", ctrl.UpdateGroup)
+		group.PUT("/:id/permissions", ctrl.UpdatePermissions)
+		group.GET("/:id/members", ctrl.GetMembers)
+		group.POST("/:id/members", ctrl.AddMembers)
+		group.DELETE("/:id/members/:userId", ctrl.RemoveMember)
+		group.PUT("/:id/members/:userId/role", ctrl.UpdateMemberRole)
+		group.POST("/:id/leave", ctrl.LeaveGroup)
+	}
+}
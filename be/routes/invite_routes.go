@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InviteRoutes untuk tautan undangan grup dan persetujuan bergabung
+func InviteRoutes(r *gin.RouterGroup, ctrl *controllers.InviteController) {
+	invites := r.Group("/invites")
+	{
+		invites.GET("/:token", ctrl.PreviewInvite)
+		invites.POST("/:token/join", ctrl.JoinViaInvite)
+	}
+
+	chats := r.Group("/chats")
+	{
+		chats.GET("/:id/invites", ctrl.GetInvites)
+		chats.POST("/:id/invites", ctrl.CreateInvite)
+		chats.DELETE("/:id/invites/:inviteId", ctrl.RevokeInvite)
+		chats.GET("/:id/join-requests", ctrl.GetJoinRequests)
+		chats.POST("/:id/join-requests/:requestId/approve", ctrl.ApproveJoinRequest)
+		chats.POST("/:id/join-requests/:requestId/reject", ctrl.RejectJoinRequest)
+	}
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessageRoutes untuk aksi per pesan (reaksi, forward, favorit, dll.)
+func MessageRoutes(r *gin.RouterGroup, ctrl *controllers.MessageController) {
+	messages := r.Group("/messages")
+	{
+		messages.GET("/starred", ctrl.GetStarredMessages)
+		messages.POST("/:id/reactions", ctrl.AddReaction)
+		messages.DELETE("/:id/reactions", ctrl.RemoveReaction)
+		messages.POST("/:id/forward", ctrl.ForwardMessage)
+		messages.GET("/:id/receipts", ctrl.GetReceipts)
+		messages.POST("/:id/poll/vote", ctrl.VotePoll)
+		messages.POST("/:id/poll/close", ctrl.ClosePoll)
+		messages.GET("/:id/poll/voters", ctrl.GetPollVoters)
+		messages.GET("/:id/thread", ctrl.GetThread)
+		messages.POST("/:id/thread/read", ctrl.MarkThreadRead)
+		messages.POST("/:id/thread/follow", ctrl.FollowThread)
+		messages.DELETE("/:id/thread/follow", ctrl.UnfollowThread)
+		messages.POST("/:id/star", ctrl.StarMessage)
+		messages.DELETE("/:id/star", ctrl.UnstarMessage)
+	}
+}
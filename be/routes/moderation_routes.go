@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+	"chat-app-be/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationRoutes untuk laporan penyalahgunaan (semua user) dan antrean moderasi (khusus moderator)
+func ModerationRoutes(r *gin.RouterGroup, ctrl *controllers.ModerationController) {
+	r.POST("/reports", ctrl.CreateReport)
+
+	moderation := r.Group("/moderation")
+	moderation.Use(middleware.ModeratorOnly())
+	{
+		moderation.GET("/reports", ctrl.GetQueue)
+		moderation.GET("/reports/:id", ctrl.GetReport)
+		moderation.POST("/reports/:id/claim", ctrl.ClaimReport)
+		moderation.POST("/reports/:id/actions", ctrl.TakeAction)
+		moderation.POST("/reports/:id/resolve", ctrl.ResolveReport)
+	}
+}
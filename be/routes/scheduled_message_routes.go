@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduledMessageRoutes untuk pesan terjadwal per chat
+func ScheduledMessageRoutes(r *gin.RouterGroup, ctrl *controllers.ScheduledMessageController) {
+	chats := r.Group("/chats")
+	{
+		chats.GET("/:id/scheduled", ctrl.List)
+		chats.POST("/:id/scheduled", ctrl.Create)
+		chats.PUT("/:id/scheduled/:scheduledId", ctrl.Update)
+		chats.DELETE("/:id/scheduled/:scheduledId", ctrl.Cancel)
+	}
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"chat-app-be/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserRoutes untuk informasi user lain (presence, blokir, dll.)
+func UserRoutes(r *gin.RouterGroup, ctrl *controllers.UserController) {
+	users := r.Group("/users")
+	{
+		users.GET("/blocked", ctrl.GetBlockedUsers)
+		users.GET("/:id/presence", ctrl.GetPresence)
+		users.POST("/:id/block", ctrl.BlockUser)
+		users.DELETE("/:id/block", ctrl.UnblockUser)
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ErrBlockedAddress dipakai saat URL preview mengarah ke alamat internal (proteksi SSRF)
+var ErrBlockedAddress = errors.New("alamat tujuan tidak diizinkan")
+
+// urlPattern menangkap URL http/https pertama di teks pesan
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+const (
+	previewMaxRedirects  = 3
+	previewTitleLength   = 200
+	previewDescLength    = 300
+	previewUserAgent     = "ChatAppLinkPreview/1.0"
+	previewDefaultMaxLen = 512 * 1024 // Cukup untuk <head> halaman pada umumnya
+)
+
+// LinkPreview adalah metadata Open Graph / Twitter card dari sebuah halaman
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// LinkPreviewFetcher mengambil metadata halaman dengan timeout, batas ukuran, dan proteksi SSRF
+type LinkPreviewFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewLinkPreviewFetcher membuat fetcher preview link.
+// allowPrivate hanya untuk development/pengujian dengan server lokal; di produksi harus false.
+func NewLinkPreviewFetcher(timeout time.Duration, maxBytes int64, allowPrivate bool) *LinkPreviewFetcher {
+	if maxBytes <= 0 {
+		maxBytes = previewDefaultMaxLen
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// Dicek pada IP yang benar-benar di-dial, jadi aman dari DNS rebinding & redirect ke alamat internal
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil, // Jangan lewat proxy env, supaya pengecekan IP tetap berlaku
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &LinkPreviewFetcher{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= previewMaxRedirects {
+					return errors.New("terlalu banyak redirect")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return ErrBlockedAddress
+				}
+				return nil
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+// ExtractURL mengambil URL http/https pertama dari teks (tanda baca di akhir kalimat dibuang)
+func ExtractURL(text string) string {
+	found := urlPattern.FindString(text)
+	return strings.TrimRight(found, ".,;:!?)]}")
+}
+
+// Fetch mengambil metadata halaman. Hanya respons HTML yang diproses.
+func (f *LinkPreviewFetcher) Fetch(ctx context.Context, rawURL string) (*LinkPreview, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("URL tidak valid: %s", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", previewUserAgent)
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return nil, fmt.Errorf("konten bukan HTML: %s", mediaType)
+	}
+
+	preview := parseMeta(io.LimitReader(resp.Body, f.maxBytes))
+	preview.URL = rawURL
+	if preview.ImageURL != "" {
+		// Gambar relatif diselesaikan terhadap URL akhir (setelah redirect)
+		if img, err := resp.Request.URL.Parse(preview.ImageURL); err == nil && (img.Scheme == "http" || img.Scheme == "https") {
+			preview.ImageURL = img.String()
+		} else {
+			preview.ImageURL = ""
+		}
+	}
+	if preview.Title == "" && preview.Description == "" && preview.ImageURL == "" {
+		return nil, errors.New("halaman tidak punya metadata preview")
+	}
+	return preview, nil
+}
+
+// parseMeta membaca <title> dan tag meta og:/twitter: dari <head>.
+// Open Graph diutamakan, Twitter card & meta description sebagai cadangan.
+func parseMeta(r io.Reader) *LinkPreview {
+	meta := make(map[string]string)
+	var title string
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return buildPreview(meta, title)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				return buildPreview(meta, title)
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if key != "" && content != "" {
+					if _, exists := meta[key]; !exists {
+						meta[key] = strings.TrimSpace(content)
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return buildPreview(meta, title)
+			}
+		}
+	}
+}
+
+// buildPreview memilih nilai terbaik dari meta yang terkumpul
+func buildPreview(meta map[string]string, title string) *LinkPreview {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	return &LinkPreview{
+		Title:       truncate(first("og:title", "twitter:title"), title, previewTitleLength),
+		Description: truncate(first("og:description", "twitter:description", "description"), "", previewDescLength),
+		ImageURL:    first("og:image", "og:image:url", "twitter:image", "twitter:image:src"),
+		SiteName:    truncate(first("og:site_name"), "", previewTitleLength),
+	}
+}
+
+// truncate memakai fallback jika value kosong lalu memotongnya per karakter
+func truncate(value, fallback string, max int) string {
+	if value == "" {
+		value = fallback
+	}
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max]) + "…"
+}
+
+// isPublicIP menolak loopback, jaringan privat, link-local (termasuk metadata cloud 169.254.169.254),
+// multicast, dan alamat kosong
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	// 100.64.0.0/10 (carrier-grade NAT) juga dianggap internal
+	if v4 := ip.To4(); v4 != nil && v4[0] == 100 && v4[1]&0xC0 == 64 {
+		return false
+	}
+	return true
+}
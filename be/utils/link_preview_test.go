@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestFetcher membuat fetcher yang boleh mengakses server httptest di loopback
+func newTestFetcher(maxBytes int64) *LinkPreviewFetcher {
+	return NewLinkPreviewFetcher(2*time.Second, maxBytes, true)
+}
+
+func serveHTML(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestFetchParsesOpenGraph(t *testing.T) {
+	srv := httptest.NewServer(serveHTML(`<!doctype html><html><head>
+		<title>Judul Cadangan</title>
+		<meta name="description" content="Deskripsi biasa">
+		<meta property="og:title" content="Judul OG">
+		<meta property="og:description" content="Deskripsi OG">
+		<meta property="og:image" content="/img/cover.png">
+		<meta property="og:site_name" content="Situs Contoh">
+		</head><body><meta property="og:title" content="Di body"></body></html>`))
+	defer srv.Close()
+
+	preview, err := newTestFetcher(0).Fetch(context.Background(), srv.URL+"/artikel")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := LinkPreview{
+		URL:         srv.URL + "/artikel",
+		Title:       "Judul OG",
+		Description: "Deskripsi OG",
+		ImageURL:    srv.URL + "/img/cover.png",
+		SiteName:    "Situs Contoh",
+	}
+	if *preview != want {
+		t.Errorf("Fetch() = %+v, want %+v", *preview, want)
+	}
+}
+
+func TestFetchFallsBackToTitleAndTwitter(t *testing.T) {
+	srv := httptest.NewServer(serveHTML(`<html><head>
+		<title> Judul Halaman </title>
+		<meta name="twitter:description" content="Dari twitter card">
+		</head></html>`))
+	defer srv.Close()
+
+	preview, err := newTestFetcher(0).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if preview.Title != "Judul Halaman" || preview.Description != "Dari twitter card" {
+		t.Errorf("Fetch() = %+v, want title & deskripsi cadangan", *preview)
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"og:title":"bukan html"}`)
+	}))
+	defer srv.Close()
+
+	if _, err := newTestFetcher(0).Fetch(context.Background(), srv.URL); err == nil {
+		t.Fatal("Fetch() error = nil untuk respons non-HTML")
+	}
+}
+
+func TestFetchStopsAtBodyLimit(t *testing.T) {
+	// Meta yang berada setelah batas ukuran tidak boleh ikut terbaca
+	padding := strings.Repeat("<!-- isi panjang -->", 100)
+	srv := httptest.NewServer(serveHTML(`<html><head><title>Awal</title>` + padding +
+		`<meta property="og:title" content="Terlambat"></head></html>`))
+	defer srv.Close()
+
+	preview, err := newTestFetcher(256).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if preview.Title != "Awal" {
+		t.Errorf("Title = %q, want %q (meta setelah batas harus diabaikan)", preview.Title, "Awal")
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	// /hop/N dialihkan ke /hop/N-1 sampai /hop/0 yang berisi halaman
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/hop/"), "%d", &n)
+		if n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+			return
+		}
+		serveHTML(`<html><head><title>Tujuan</title></head></html>`)(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := newTestFetcher(0)
+	if _, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/hop/%d", srv.URL, previewMaxRedirects-1)); err != nil {
+		t.Fatalf("Fetch() dengan %d redirect error = %v", previewMaxRedirects-1, err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/hop/%d", srv.URL, previewMaxRedirects)); err == nil {
+		t.Fatalf("Fetch() dengan %d redirect error = nil, want gagal", previewMaxRedirects)
+	}
+}
+
+func TestFetchBlocksPrivateAddress(t *testing.T) {
+	var hit atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit.Store(true)
+		serveHTML(`<html><head><title>Internal</title></head></html>`)(w, r)
+	}))
+	defer srv.Close()
+
+	fetcher := NewLinkPreviewFetcher(2*time.Second, 0, false)
+	_, err := fetcher.Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Fetch() error = %v, want ErrBlockedAddress", err)
+	}
+	if hit.Load() {
+		t.Fatal("server loopback tetap dihubungi")
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"fc00::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestExtractURL(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"lihat https://contoh.com/a?b=1.", "https://contoh.com/a?b=1"},
+		{"(http://contoh.com)", "http://contoh.com"},
+		{"tanpa tautan", ""},
+		{"ftp://contoh.com", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ExtractURL(tt.text); got != tt.want {
+			t.Errorf("ExtractURL(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
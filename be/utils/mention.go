@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern menangkap @username yang diawali awal teks atau karakter non-kata
+// (supaya alamat email seperti a@b.com tidak terbaca sebagai mention)
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{3,30})\b`)
+
+// MentionAll adalah kata kunci untuk me-mention semua anggota chat
+const MentionAll = "all"
+
+// ParseMentions mengambil daftar username unik (huruf kecil) yang di-mention dalam teks,
+// serta apakah teks memuat @all.
+func ParseMentions(text string) (usernames []string, all bool) {
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.ToLower(match[1])
+		if username == MentionAll {
+			all = true
+			continue
+		}
+		if !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames, all
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParsePagination membaca query ?page=&limit= dengan nilai default dan batas aman.
+// Mengembalikan nomor halaman, limit, dan jumlah data yang dilewati (skip).
+func ParsePagination(ctx *gin.Context) (page, limit, skip int) {
+	page, _ = strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit, (page - 1) * limit
+}
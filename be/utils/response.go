@@ -41,19 +41,7 @@ func ErrorResponse(ctx *gin.Context, code int, message string, err error) {
 	if err != nil {
 		// Log error lengkap di server (Internal)
 		log.Printf("[API ERROR] %s: %v", message, err)
-		
-		// Jika mode Release, sembunyikan detail teknis
-		// ATAU jika pesan error terlihat mengandung rahasia database/ORM
-		lowErr := strings.ToLower(err.Error())
-		if os.Getenv("GIN_MODE") == "release" || 
-		   strings.Contains(lowErr, "prisma") || 
-		   strings.Contains(lowErr, "connector") || 
-		   strings.Contains(lowErr, "record") || 
-		   strings.Contains(lowErr, "relation") {
-			errStr = "Terjadi kesalahan server"
-		} else {
-			errStr = err.Error()
-		}
+		errStr = SafeErrorMessage(err)
 	}
 
 	ctx.AbortWithStatusJSON(code, Response{
@@ -63,6 +51,21 @@ func ErrorResponse(ctx *gin.Context, code int, message string, err error) {
 	})
 }
 
+// SafeErrorMessage mengubah error jadi teks yang aman ditampilkan ke client.
+// Jika mode Release, sembunyikan detail teknis
+// ATAU jika pesan error terlihat mengandung rahasia database/ORM
+func SafeErrorMessage(err error) string {
+	lowErr := strings.ToLower(err.Error())
+	if os.Getenv("GIN_MODE") == "release" ||
+		strings.Contains(lowErr, "prisma") ||
+		strings.Contains(lowErr, "connector") ||
+		strings.Contains(lowErr, "record") ||
+		strings.Contains(lowErr, "relation") {
+		return "Terjadi kesalahan server"
+	}
+	return err.Error()
+}
+
 // BadRequest helper untuk error input
 func BadRequest(ctx *gin.Context, message string, err error) {
 	ErrorResponse(ctx, http.StatusBadRequest, message, err)
@@ -87,6 +90,16 @@ func ValidationErrorResponse(ctx *gin.Context, errors interface{}) {
 	})
 }
 
+// Forbidden helper untuk user yang login tapi tidak berhak mengakses resource (403)
+func Forbidden(ctx *gin.Context, message string, err error) {
+	ErrorResponse(ctx, http.StatusForbidden, message, err)
+}
+
+// NotFound helper untuk resource yang tidak ditemukan (404)
+func NotFound(ctx *gin.Context, message string, err error) {
+	ErrorResponse(ctx, http.StatusNotFound, message, err)
+}
+
 // Conflict helper untuk error data duplikat (409)
 func Conflict(ctx *gin.Context, message string, err error) {
 	ErrorResponse(ctx, http.StatusConflict, message, err)
@@ -44,6 +44,8 @@ func getErrorMsg(fe validator.FieldError) string {
 		return "Harus berupa angka"
 	case "len":
 		return fmt.Sprintf("Panjang harus tepat %s karakter", fe.Param())
+	case "alphanum":
+		return "Hanya boleh huruf dan angka"
 	}
 	return "Input tidak valid"
 }
@@ -155,6 +155,7 @@ class _GroupChatPageState extends State<GroupChatPage> {
       "chat", 
       widget.chat.id, 
       content,
+      replyToId: newMessage.replyToId, // _replyMessage sudah dikosongkan di setState di atas
     );
   }
 
@@ -164,7 +164,7 @@ class _SingleChatPageState extends State<SingleChatPage> {
       "chat", 
       widget.chat.id, 
       content,
-      data: _replyMessage?.id, // Kirim ID pesan yang dibalas sebagai data tambahan
+      replyToId: newMessage.replyToId, // _replyMessage sudah dikosongkan di setState di atas
     );
   }
 
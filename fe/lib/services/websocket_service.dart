@@ -26,12 +26,13 @@ class WebSocketService {
 
   Stream get messages => _channel?.stream ?? const Stream.empty();
 
-  void sendMessage(String type, String chatId, String content, {dynamic data}) {
+  void sendMessage(String type, String chatId, String content, {dynamic data, String? replyToId}) {
     if (_channel != null) {
       final msg = jsonEncode({
         'type': type,
         'chatId': chatId,
         'content': content,
+        if (replyToId != null) 'replyToId': replyToId,
         'data': data,
       });
       _channel!.sink.add(msg);